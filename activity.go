@@ -0,0 +1,62 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ActivityTracker records when each session's screen last changed. Create one with TrackActivity.
+type ActivityTracker struct {
+	mx   *sync.Mutex
+	last map[string]time.Time
+}
+
+// TrackActivity starts recording the screen updates of all sessions, until the context is done or the Connection is
+// closed. Sessions are known only after their first update since the tracker started.
+func TrackActivity(ctx context.Context, conn *Connection) (*ActivityTracker, error) {
+	updates, err := MonitorScreenUpdates(ctx, conn, AllSessions)
+	if err != nil {
+		return nil, fmt.Errorf("track activity: %w", err)
+	}
+
+	t := &ActivityTracker{mx: &sync.Mutex{}, last: make(map[string]time.Time)}
+
+	go func() {
+		for update := range updates {
+			t.mx.Lock()
+			t.last[update.GetSession()] = time.Now()
+			t.mx.Unlock()
+		}
+	}()
+
+	return t, nil
+}
+
+// LastActivity returns the time of the last screen update of a session, or the zero time if there was none.
+func (t *ActivityTracker) LastActivity(sessionId string) time.Time {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return t.last[sessionId]
+}
+
+// TabKey returns a TabKeyFunc sorting tabs by the last activity of their sessions, least recent first; sort in reverse
+// to get the most recently active tabs first.
+func (t *ActivityTracker) TabKey() TabKeyFunc {
+	return func(tab *Tab) (TabKey, error) {
+		sessionIds, err := tab.SessionIds()
+		if err != nil {
+			return nil, err
+		}
+
+		var last time.Time
+		for _, id := range sessionIds {
+			if activity := t.LastActivity(id); activity.After(last) {
+				last = activity
+			}
+		}
+
+		return TimeKey(last), nil
+	}
+}
@@ -45,6 +45,9 @@ type App struct {
 
 	active        bool
 	activeSession *Session
+
+	locations     *VariableWatcher
+	stopLocations context.CancelFunc
 }
 
 // NewApp creates a new App bound to the given Connection.
@@ -244,11 +247,24 @@ func (a *App) SelectedTabId() (string, error) {
 
 // CreateTab creates a new tab in the targeted window, at the specified index, with the Default or named profile.
 func (a *App) CreateTab(windowId string, tabIndex uint32, profileName string) (*iterm2.CreateTabResponse, error) {
+	return a.CreateTabWithProfile(windowId, tabIndex, profileName)
+}
+
+// CreateTabWithProfile creates a new tab like CreateTab, modifying the profile of its session with the given
+// customizations, such as a working directory, without changing the underlying profile.
+func (a *App) CreateTabWithProfile(windowId string, tabIndex uint32, profileName string,
+	customizations ...Assignment) (*iterm2.CreateTabResponse, error) {
+
 	if profileName == "" {
 		profileName = DefaultProfileName
 	}
 
-	createReq := &iterm2.CreateTabRequest{}
+	properties, err := profileProperties(customizations)
+	if err != nil {
+		return nil, fmt.Errorf("create tab: %w", err)
+	}
+
+	createReq := &iterm2.CreateTabRequest{CustomProfileProperties: properties}
 	createReq.TabIndex = &tabIndex
 
 	if windowId != "" {
@@ -299,6 +315,27 @@ func (a *App) Session(id string) *Session {
 	return nil
 }
 
+// SessionById returns the Session with the given ID, which can be a session the App doesn't track, such as one created
+// by another client. The ID is not checked: methods of a Session that does not exist return errors.
+func (a *App) SessionById(id string) *Session {
+	if s := a.Session(id); s != nil {
+		return s
+	}
+
+	return newSession(id, a, a.conn, false)
+}
+
+// Tab returns the Tab with the given ID. The ID is not checked: methods of a Tab that does not exist return errors.
+func (a *App) Tab(id string) *Tab {
+	return &Tab{id: id, app: a, conn: a.conn}
+}
+
+// Window returns the Window with the given ID. The ID is not checked: methods of a Window that does not exist return
+// errors.
+func (a *App) Window(id string) *Window {
+	return &Window{id: id, app: a, conn: a.conn}
+}
+
 func (a *App) ActiveSession() *Session {
 	a.mx.Lock()
 	defer a.mx.Unlock()
@@ -322,6 +359,21 @@ func (a *App) ListSessions() (*iterm2.ListSessionsResponse, error) {
 	return resp.GetListSessionsResponse(), nil
 }
 
+// splitTreeSessions returns the sessions found in a tab's split tree, in the order they're laid out.
+func splitTreeSessions(node *iterm2.SplitTreeNode) []*iterm2.SessionSummary {
+	var sessions []*iterm2.SessionSummary
+
+	for _, link := range node.GetLinks() {
+		if link.GetSession() != nil {
+			sessions = append(sessions, link.GetSession())
+		} else if link.GetNode() != nil {
+			sessions = append(sessions, splitTreeSessions(link.GetNode())...)
+		}
+	}
+
+	return sessions
+}
+
 func (a *App) sendActivateRequest(activateReq *iterm2.ActivateRequest) error {
 	req := &iterm2.ClientOriginatedMessage{
 		Submessage: &iterm2.ClientOriginatedMessage_ActivateRequest{
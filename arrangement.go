@@ -0,0 +1,136 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+)
+
+var (
+	ErrArrangementNotFound = fmt.Errorf("SavedArrangementResponse_ARRANGEMENT_NOT_FOUND")
+	ErrWindowNotFound      = fmt.Errorf("SavedArrangementResponse_WINDOW_NOT_FOUND")
+)
+
+// RestoredArrangement holds the IDs of the windows, tabs and sessions created by restoring an arrangement. When an
+// arrangement is restored into an existing window, WindowIds is empty.
+type RestoredArrangement struct {
+	WindowIds  []string
+	TabIds     []string
+	SessionIds []string
+}
+
+// SaveArrangement saves all windows as a named arrangement, or only the window with the given ID if windowId is not
+// empty. An existing arrangement with the same name is replaced.
+// See https://iterm2.com/python-api/arrangement.html.
+func (a *App) SaveArrangement(name string, windowId string) error {
+	_, err := a.sendSavedArrangementRequest(iterm2.SavedArrangementRequest_SAVE, name, windowId)
+	if err != nil {
+		return fmt.Errorf("save arrangement %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// RestoreArrangement restores the named arrangement in new windows, or as new tabs of the window with the given ID if
+// intoWindowId is not empty. It returns the IDs of the windows, tabs and sessions the restoration created.
+func (a *App) RestoreArrangement(name string, intoWindowId string) (*RestoredArrangement, error) {
+	before, err := a.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("restore arrangement %q: %w", name, err)
+	}
+
+	_, err = a.sendSavedArrangementRequest(iterm2.SavedArrangementRequest_RESTORE, name, intoWindowId)
+	if err != nil {
+		return nil, fmt.Errorf("restore arrangement %q: %w", name, err)
+	}
+
+	after, err := a.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("restore arrangement %q: %w", name, err)
+	}
+
+	return diffLayouts(before, after), nil
+}
+
+// Arrangements returns the names of the saved arrangements.
+func (a *App) Arrangements() ([]string, error) {
+	resp, err := a.sendSavedArrangementRequest(iterm2.SavedArrangementRequest_LIST, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("list arrangements: %w", err)
+	}
+
+	return resp.GetNames(), nil
+}
+
+func (a *App) sendSavedArrangementRequest(action iterm2.SavedArrangementRequest_Action, name string,
+	windowId string) (*iterm2.SavedArrangementResponse, error) {
+
+	arrangementReq := &iterm2.SavedArrangementRequest{Action: &action}
+
+	if name != "" {
+		arrangementReq.Name = &name
+	}
+
+	if windowId != "" {
+		arrangementReq.WindowId = &windowId
+	}
+
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_SavedArrangementRequest{
+			SavedArrangementRequest: arrangementReq,
+		},
+	}
+
+	resp, err := a.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.GetSavedArrangementResponse().GetStatus() {
+	case iterm2.SavedArrangementResponse_OK:
+		return resp.GetSavedArrangementResponse(), nil
+	case iterm2.SavedArrangementResponse_ARRANGEMENT_NOT_FOUND:
+		return nil, ErrArrangementNotFound
+	case iterm2.SavedArrangementResponse_WINDOW_NOT_FOUND:
+		return nil, ErrWindowNotFound
+	default:
+		return nil, fmt.Errorf("%s", resp.GetSavedArrangementResponse().GetStatus())
+	}
+}
+
+// diffLayouts returns the windows, tabs and sessions found in after but not in before, in layout order.
+func diffLayouts(before, after *iterm2.ListSessionsResponse) *RestoredArrangement {
+	known := make(map[string]bool)
+
+	for _, win := range before.GetWindows() {
+		known[win.GetWindowId()] = true
+		for _, tab := range win.GetTabs() {
+			known[tab.GetTabId()] = true
+			for _, s := range splitTreeSessions(tab.GetRoot()) {
+				known[s.GetUniqueIdentifier()] = true
+			}
+		}
+	}
+
+	restored := &RestoredArrangement{}
+
+	for _, win := range after.GetWindows() {
+		if !known[win.GetWindowId()] {
+			restored.WindowIds = append(restored.WindowIds, win.GetWindowId())
+		}
+
+		for _, tab := range win.GetTabs() {
+			if !known[tab.GetTabId()] {
+				restored.TabIds = append(restored.TabIds, tab.GetTabId())
+			}
+
+			for _, s := range splitTreeSessions(tab.GetRoot()) {
+				if !known[s.GetUniqueIdentifier()] {
+					restored.SessionIds = append(restored.SessionIds, s.GetUniqueIdentifier())
+				}
+			}
+		}
+	}
+
+	return restored
+}
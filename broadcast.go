@@ -0,0 +1,128 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+)
+
+var (
+	ErrBroadcastSessionNotFound    = fmt.Errorf("SetBroadcastDomainsResponse_SESSION_NOT_FOUND")
+	ErrBroadcastDomainsNotDisjoint = fmt.Errorf("SetBroadcastDomainsResponse_BROADCAST_DOMAINS_NOT_DISJOINT")
+	ErrSessionsNotInSameWindow     = fmt.Errorf("SetBroadcastDomainsResponse_SESSIONS_NOT_IN_SAME_WINDOW")
+)
+
+// BroadcastDomain is a group of sessions, identified by their IDs, that share their keyboard input.
+// See https://iterm2.com/python-api/broadcast.html.
+type BroadcastDomain []string
+
+// Contains tells if the session with the given ID is part of the domain.
+func (d BroadcastDomain) Contains(sessionId string) bool {
+	for _, id := range d {
+		if id == sessionId {
+			return true
+		}
+	}
+	return false
+}
+
+// BroadcastDomains returns the current broadcast domains.
+func (a *App) BroadcastDomains() ([]BroadcastDomain, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_GetBroadcastDomainsRequest{
+			GetBroadcastDomainsRequest: &iterm2.GetBroadcastDomainsRequest{},
+		},
+	}
+
+	resp, err := a.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("broadcast domains: %w", err)
+	}
+
+	return newBroadcastDomains(resp.GetGetBroadcastDomainsResponse().GetBroadcastDomains()), nil
+}
+
+// SetBroadcastDomains replaces all broadcast domains with the given ones; with no domain, broadcasting is turned off
+// everywhere. Domains must be disjoint, and the sessions of a domain must be in the same window.
+func (a *App) SetBroadcastDomains(domains ...BroadcastDomain) error {
+	setReq := &iterm2.SetBroadcastDomainsRequest{}
+	for _, d := range domains {
+		setReq.BroadcastDomains = append(setReq.BroadcastDomains, &iterm2.BroadcastDomain{SessionIds: d})
+	}
+
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_SetBroadcastDomainsRequest{
+			SetBroadcastDomainsRequest: setReq,
+		},
+	}
+
+	resp, err := a.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("set broadcast domains: %w", err)
+	}
+
+	switch resp.GetSetBroadcastDomainsResponse().GetStatus() {
+	case iterm2.SetBroadcastDomainsResponse_OK:
+		return nil
+	case iterm2.SetBroadcastDomainsResponse_SESSION_NOT_FOUND:
+		return fmt.Errorf("set broadcast domains: %w", ErrBroadcastSessionNotFound)
+	case iterm2.SetBroadcastDomainsResponse_BROADCAST_DOMAINS_NOT_DISJOINT:
+		return fmt.Errorf("set broadcast domains: %w", ErrBroadcastDomainsNotDisjoint)
+	case iterm2.SetBroadcastDomainsResponse_SESSIONS_NOT_IN_SAME_WINDOW:
+		return fmt.Errorf("set broadcast domains: %w", ErrSessionsNotInSameWindow)
+	default:
+		return fmt.Errorf("set broadcast domains: %s", resp.GetSetBroadcastDomainsResponse().GetStatus())
+	}
+}
+
+// SendTextTo sends text to exactly the sessions with the given IDs, never broadcasting it to other sessions. The text
+// is sent within a transaction, so that no other request is handled by iTerm2 in between.
+func (a *App) SendTextTo(text string, sessionIds ...string) error {
+	tx, err := a.conn.Transaction()
+	if err != nil {
+		return fmt.Errorf("send text: %w", err)
+	}
+
+	for _, id := range sessionIds {
+		if err := newSession(id, a, a.conn, false).SendText(text, false); err != nil {
+			_ = tx.End()
+			return fmt.Errorf("send text: session %s: %w", id, err)
+		}
+	}
+
+	if err := tx.End(); err != nil {
+		return fmt.Errorf("send text: %w", err)
+	}
+
+	return nil
+}
+
+// MonitorBroadcastDomains subscribes to BroadcastDomainsChangedNotification and writes the new broadcast domains to the
+// returned channel each time they change, until the context is done or the Connection is closed.
+func MonitorBroadcastDomains(ctx context.Context, conn *Connection) (<-chan []BroadcastDomain, error) {
+	recv, err := conn.Subscribe(ctx, NewNotificationRequest(true, iterm2.NotificationType_NOTIFY_ON_BROADCAST_CHANGE, ""))
+	if err != nil {
+		return nil, fmt.Errorf("broadcast domains monitor: %w", err)
+	}
+
+	changes := make(chan []BroadcastDomain)
+
+	go func() {
+		for msg := range recv.Ch() {
+			if n := msg.GetNotification().GetBroadcastDomainsChanged(); n != nil {
+				changes <- newBroadcastDomains(n.GetBroadcastDomains())
+			}
+		}
+		close(changes)
+	}()
+
+	return changes, nil
+}
+
+func newBroadcastDomains(domains []*iterm2.BroadcastDomain) []BroadcastDomain {
+	var result []BroadcastDomain
+	for _, d := range domains {
+		result = append(result, d.GetSessionIds())
+	}
+	return result
+}
@@ -0,0 +1,130 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+)
+
+var ErrColorPresetNotFound = fmt.Errorf("ColorPresetResponse_PRESET_NOT_FOUND")
+
+// ColorSetting is one of the colors of a ColorPreset. The key is the name of the profile property the color applies to,
+// such as "Background Color" or "Ansi 1 Color".
+type ColorSetting struct {
+	Key   string
+	Color Color
+}
+
+// ColorPreset is a named set of colors that can be applied to profiles.
+// See https://iterm2.com/python-api/colorpresets.html.
+type ColorPreset struct {
+	Name     string
+	Settings []ColorSetting
+}
+
+// Assignments returns the profile property assignments that apply the preset's colors.
+func (p *ColorPreset) Assignments() []Assignment {
+	var assignments []Assignment
+	for _, s := range p.Settings {
+		assignments = append(assignments, Assignment{Key: s.Key, Value: s.Color})
+	}
+	return assignments
+}
+
+// ColorPresets lists the names of the available color presets.
+func (a *App) ColorPresets() ([]string, error) {
+	resp, err := sendColorPresetRequest(a.conn, &iterm2.ColorPresetRequest{
+		Request: &iterm2.ColorPresetRequest_ListPresets_{ListPresets: &iterm2.ColorPresetRequest_ListPresets{}},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("list color presets: %w", err)
+	}
+
+	return resp.GetListPresets().GetName(), nil
+}
+
+// ColorPreset returns the named color preset, or ErrColorPresetNotFound.
+func (a *App) ColorPreset(name string) (*ColorPreset, error) {
+	return getColorPreset(a.conn, name)
+}
+
+// ApplyColorPreset applies the named color preset to the session's copy of its profile, with a single request.
+func (s *Session) ApplyColorPreset(name string) error {
+	preset, err := getColorPreset(s.conn, name)
+	if err != nil {
+		return err
+	}
+
+	return s.SetProfileProperties(preset.Assignments()...)
+}
+
+// ApplyColorPreset applies the named color preset to the profile, with a single request. See Profile.SetProperties.
+func (p *Profile) ApplyColorPreset(name string) error {
+	preset, err := getColorPreset(p.conn, name)
+	if err != nil {
+		return err
+	}
+
+	return p.SetProperties(preset.Assignments()...)
+}
+
+func getColorPreset(conn *Connection, name string) (*ColorPreset, error) {
+	resp, err := sendColorPresetRequest(conn, &iterm2.ColorPresetRequest{
+		Request: &iterm2.ColorPresetRequest_GetPreset_{GetPreset: &iterm2.ColorPresetRequest_GetPreset{Name: &name}},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("get color preset %q: %w", name, err)
+	}
+
+	return NewColorPreset(name, resp.GetGetPreset()), nil
+}
+
+// NewColorPreset creates a ColorPreset from the colors of a ColorPresetResponse. Colors without an alpha component are
+// opaque.
+func NewColorPreset(name string, resp *iterm2.ColorPresetResponse_GetPreset) *ColorPreset {
+	preset := &ColorPreset{Name: name}
+
+	for _, s := range resp.GetColorSettings() {
+		alpha := 1.0
+		if s.Alpha != nil {
+			alpha = float64(s.GetAlpha())
+		}
+
+		preset.Settings = append(preset.Settings, ColorSetting{
+			Key: s.GetKey(),
+			Color: Color{
+				Red:        float64(s.GetRed()),
+				Green:      float64(s.GetGreen()),
+				Blue:       float64(s.GetBlue()),
+				Alpha:      alpha,
+				ColorSpace: s.GetColorSpace(),
+			},
+		})
+	}
+
+	return preset
+}
+
+func sendColorPresetRequest(conn *Connection, presetReq *iterm2.ColorPresetRequest) (*iterm2.ColorPresetResponse, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_ColorPresetRequest{
+			ColorPresetRequest: presetReq,
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.GetColorPresetResponse().GetStatus() {
+	case iterm2.ColorPresetResponse_OK:
+		return resp.GetColorPresetResponse(), nil
+	case iterm2.ColorPresetResponse_PRESET_NOT_FOUND:
+		return nil, ErrColorPresetNotFound
+	default:
+		return nil, fmt.Errorf("%s", resp.GetColorPresetResponse().GetStatus())
+	}
+}
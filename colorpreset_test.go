@@ -0,0 +1,54 @@
+package itermctl_test
+
+import (
+	"github.com/golang/protobuf/proto"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"testing"
+)
+
+func TestColorPreset_Assignments(t *testing.T) {
+	background := itermctl.NewColor(0, 0, 0)
+	red := itermctl.NewColor(255, 0, 0)
+
+	preset := &itermctl.ColorPreset{
+		Name: "Test",
+		Settings: []itermctl.ColorSetting{
+			{Key: itermctl.ProfileBackgroundColor, Color: background},
+			{Key: itermctl.AnsiColorKey(1), Color: red},
+		},
+	}
+
+	expected := []itermctl.Assignment{
+		{Key: "Background Color", Value: background},
+		{Key: "Ansi 1 Color", Value: red},
+	}
+
+	if assignments := preset.Assignments(); !reflect.DeepEqual(assignments, expected) {
+		t.Fatalf("expected %v, got %v", expected, assignments)
+	}
+}
+
+func TestNewColorPreset(t *testing.T) {
+	resp := &iterm2.ColorPresetResponse_GetPreset{
+		ColorSettings: []*iterm2.ColorPresetResponse_GetPreset_ColorSetting{
+			{Key: proto.String("Background Color"), Red: proto.Float32(0.5), Green: proto.Float32(0), Blue: proto.Float32(1), Alpha: proto.Float32(0.25),
+				ColorSpace: proto.String(itermctl.ColorSpaceSRGB)},
+			{Key: proto.String("Ansi 1 Color"), Red: proto.Float32(1), Green: proto.Float32(0), Blue: proto.Float32(0)},
+		},
+	}
+
+	expected := &itermctl.ColorPreset{
+		Name: "Test",
+		Settings: []itermctl.ColorSetting{
+			{Key: "Background Color", Color: itermctl.Color{Red: 0.5, Blue: 1, Alpha: 0.25,
+				ColorSpace: itermctl.ColorSpaceSRGB}},
+			{Key: "Ansi 1 Color", Color: itermctl.Color{Red: 1, Alpha: 1}},
+		},
+	}
+
+	if preset := itermctl.NewColorPreset("Test", resp); !reflect.DeepEqual(preset, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, preset)
+	}
+}
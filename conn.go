@@ -668,6 +668,8 @@ func getNotificationType(n *iterm2.Notification) iterm2.NotificationType {
 		return iterm2.NotificationType_NOTIFY_ON_VARIABLE_CHANGE
 	} else if n.GetPromptNotification() != nil {
 		return iterm2.NotificationType_NOTIFY_ON_PROMPT
+	} else if n.GetLocationChangeNotification() != nil {
+		return iterm2.NotificationType_NOTIFY_ON_LOCATION_CHANGE
 	}
 	return 0
 }
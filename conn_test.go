@@ -48,6 +48,19 @@ func TestAcceptNotificationType(t *testing.T) {
 
 }
 
+func TestAcceptNotificationType_LocationChange(t *testing.T) {
+	msg := &iterm2.ServerOriginatedMessage{
+		Submessage: &iterm2.ServerOriginatedMessage_Notification{Notification: &iterm2.Notification{
+			LocationChangeNotification: &iterm2.LocationChangeNotification{},
+		}},
+	}
+
+	f := itermctl.AcceptNotificationType(iterm2.NotificationType_NOTIFY_ON_LOCATION_CHANGE)
+	if !f(msg) {
+		t.Fatal("expected a LocationChangeNotification to be accepted")
+	}
+}
+
 func TestNewNotificationRequest(t *testing.T) {
 	examples := []struct {
 		t               iterm2.NotificationType
@@ -0,0 +1,57 @@
+package expect
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// Case is a possible turn of a Dialogue.
+type Case struct {
+	Pattern *regexp.Regexp
+	// Reply is sent, followed by a line break, when Pattern matches; nothing is sent if it's empty.
+	Reply string
+	// Done ends the Dialogue successfully after Pattern matches and Reply is sent.
+	Done bool
+	// Err ends the Dialogue with this error when Pattern matches, for example on "Permission denied".
+	Err error
+}
+
+// Dialogue replies to the session's output according to the cases, until a Done case or an Err case matches. It returns
+// the last match. For example, logging in with ssh:
+//
+//	match, err := e.Dialogue(ctx,
+//		expect.Case{Pattern: regexp.MustCompile(`\(yes/no.*\)\?`), Reply: "yes"},
+//		expect.Case{Pattern: regexp.MustCompile(`(?i)password:`), Reply: password},
+//		expect.Case{Pattern: regexp.MustCompile(`Permission denied`), Err: ErrLoginFailed},
+//		expect.Case{Pattern: regexp.MustCompile(`\$ $`), Done: true},
+//	)
+func (e *Expecter) Dialogue(ctx context.Context, cases ...Case) (*Match, error) {
+	var patterns []*regexp.Regexp
+	for _, c := range cases {
+		patterns = append(patterns, c.Pattern)
+	}
+
+	for {
+		m, err := e.Expect(ctx, patterns...)
+		if err != nil {
+			return nil, fmt.Errorf("dialogue: %w", err)
+		}
+
+		c := cases[m.Index]
+
+		if c.Err != nil {
+			return m, c.Err
+		}
+
+		if c.Reply != "" {
+			if err := e.SendLine(c.Reply); err != nil {
+				return m, fmt.Errorf("dialogue: %w", err)
+			}
+		}
+
+		if c.Done {
+			return m, nil
+		}
+	}
+}
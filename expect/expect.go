@@ -0,0 +1,240 @@
+// Package expect drives interactive programs running in iTerm2 sessions, such as ssh password prompts, REPLs or
+// installers, by waiting for their output to match regular expressions and sending them input, like expect(1).
+//
+// Matching works on the screen contents, so it doesn't need shell integration. The text given to the regular
+// expressions starts after the end of the previous match (or at the cursor position when the Expecter was created),
+// joins soft-wrapped lines, separates the others with "\n", and ends with the line of the cursor, without a trailing
+// line break. Use the (?m) flag to anchor expressions to line boundaries. Empty matches, such as those of "$" or "x*",
+// are ignored, since they would consume no output.
+//
+// The Expect function waits for a single match. Sequences of matches and replies need an Expecter, created with New,
+// which remembers the output already consumed. Both watch the screen for updates through the session's Connection.
+package expect
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/screen"
+	"regexp"
+	"time"
+)
+
+// DefaultTimeout is the Timeout of new Expecters.
+const DefaultTimeout = 30 * time.Second
+
+var (
+	// ErrTimeout is returned when nothing matches within an Expecter's Timeout. It wraps context.DeadlineExceeded.
+	ErrTimeout   = fmt.Errorf("timeout waiting for a match: %w", context.DeadlineExceeded)
+	ErrNoPattern = fmt.Errorf("no pattern to match")
+)
+
+// Match is the output matched by one of the regular expressions given to Expecter.Expect.
+type Match struct {
+	// Index is the index of the regular expression that matched.
+	Index int
+	// Text is the matched text, and Groups are the texts matched by its capturing groups, Groups[0] being Text.
+	Text   string
+	Groups []string
+	// Range is the range of cells showing Text.
+	Range screen.CoordRange
+}
+
+// Expecter matches the output of a session. Create one with New.
+type Expecter struct {
+	// Timeout limits how long Expect waits for a match; zero means no limit other than the context's.
+	Timeout time.Duration
+
+	session  *itermctl.Session
+	updates  chan struct{}
+	consumed screen.Coord
+	cancel   context.CancelFunc
+}
+
+// Expect waits until the output that the session shows after the call matches one of the given regular expressions,
+// within DefaultTimeout, and returns the match. See Expecter.Expect.
+func Expect(ctx context.Context, session *itermctl.Session, patterns ...*regexp.Regexp) (*Match, error) {
+	e, err := New(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	defer e.Close()
+
+	return e.Expect(ctx, patterns...)
+}
+
+// New creates an Expecter for a session, matching the output that follows the current cursor position. The Expecter
+// watches the session's screen until the context is done or Close is called.
+func New(ctx context.Context, session *itermctl.Session) (*Expecter, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	notifications, err := itermctl.MonitorScreenUpdates(ctx, session.Conn(), session.Id())
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("expect: %w", err)
+	}
+
+	lines, err := session.TrailingLines(1)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("expect: %w", err)
+	}
+
+	e := &Expecter{
+		Timeout:  DefaultTimeout,
+		session:  session,
+		updates:  make(chan struct{}, 1),
+		consumed: screen.NewCoord(lines.GetCursor()),
+		cancel:   cancel,
+	}
+
+	// coalesce screen updates, so that a slow reader doesn't hold up the Connection
+	go func() {
+		for range notifications {
+			select {
+			case e.updates <- struct{}{}:
+			default:
+			}
+		}
+
+		close(e.updates)
+	}()
+
+	return e, nil
+}
+
+// Close stops watching the session.
+func (e *Expecter) Close() {
+	e.cancel()
+}
+
+// Expect waits until the session's new output matches one of the given regular expressions, and returns the earliest
+// non-empty match; if several expressions match at the same position, the first one wins. The output up to the end of the match
+// is consumed, so that it isn't matched again. An error wrapping ErrTimeout is returned if nothing matches within the
+// Expecter's Timeout, and one wrapping the context's error if the context is done first.
+func (e *Expecter) Expect(ctx context.Context, patterns ...*regexp.Regexp) (*Match, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("expect: %w", ErrNoPattern)
+	}
+
+	var timeout <-chan time.Time
+	if e.Timeout > 0 {
+		timer := time.NewTimer(e.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		scr, err := e.unconsumed()
+		if err != nil {
+			return nil, fmt.Errorf("expect: %w", err)
+		}
+
+		if m, end, ok := match(scr, e.consumed, patterns); ok {
+			e.consumed = end
+			return m, nil
+		}
+
+		select {
+		case _, ok := <-e.updates:
+			if !ok {
+				return nil, fmt.Errorf("expect: %w", itermctl.ErrClosed)
+			}
+		case <-timeout:
+			return nil, fmt.Errorf("expect: %w", ErrTimeout)
+		case <-ctx.Done():
+			return nil, fmt.Errorf("expect: %w", ctx.Err())
+		}
+	}
+}
+
+// Send sends text to the session, as if it was typed.
+func (e *Expecter) Send(text string) error {
+	return e.session.SendText(text, false)
+}
+
+// SendLine sends text to the session followed by a line break, as if it was typed followed by Return.
+func (e *Expecter) SendLine(text string) error {
+	return e.session.SendText(text+"\n", false)
+}
+
+// unconsumed fetches the lines from the first unconsumed one to the line of the cursor.
+func (e *Expecter) unconsumed() (*screen.Screen, error) {
+	nl, err := e.session.NumberOfLines()
+	if err != nil {
+		return nil, err
+	}
+
+	first := int64(nl.Overflow)
+	if e.consumed.Y < first {
+		// lines were dropped from the history before they could be matched
+		e.consumed = screen.Coord{Y: first}
+	}
+
+	end := first + int64(nl.History) + int64(nl.Grid)
+	resp, err := e.session.ScreenContents(screen.LineRange(e.consumed.Y, end).Windowed().Proto())
+	if err != nil {
+		return nil, err
+	}
+
+	if cursorLines := resp.GetCursor().GetY() - e.consumed.Y + 1; cursorLines >= 0 && cursorLines < int64(len(resp.Contents)) {
+		resp.Contents = resp.Contents[:cursorLines]
+	}
+
+	return screen.NewAt(resp, e.consumed.Y), nil
+}
+
+// match finds the earliest non-empty match of the patterns in the text of the screen following from, and returns it
+// with the position following the match.
+func match(scr *screen.Screen, from screen.Coord, patterns []*regexp.Regexp) (*Match, screen.Coord, bool) {
+	start, ok := scr.Offset(from)
+	if !ok {
+		return nil, screen.Coord{}, false
+	}
+
+	text := scr.Text[start:]
+	if len(text) > 0 && text[len(text)-1] == '\n' {
+		text = text[:len(text)-1]
+	}
+
+	var best *Match
+	var bestLoc []int
+
+	for i, pattern := range patterns {
+		loc := firstNonEmpty(pattern, text)
+		if loc == nil || bestLoc != nil && loc[0] >= bestLoc[0] {
+			continue
+		}
+
+		m := &Match{Index: i, Text: text[loc[0]:loc[1]]}
+		for g := 0; g < len(loc); g += 2 {
+			if loc[g] < 0 {
+				m.Groups = append(m.Groups, "")
+			} else {
+				m.Groups = append(m.Groups, text[loc[g]:loc[g+1]])
+			}
+		}
+
+		best, bestLoc = m, loc
+	}
+
+	if best == nil {
+		return nil, screen.Coord{}, false
+	}
+
+	best.Range, _ = scr.CoordRange(start+bestLoc[0], start+bestLoc[1])
+	end, _ := scr.Coord(start + bestLoc[1])
+
+	return best, end, true
+}
+
+// firstNonEmpty returns the submatch indexes of the first match of a pattern that isn't empty.
+func firstNonEmpty(pattern *regexp.Regexp, text string) []int {
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		if loc[1] > loc[0] {
+			return loc
+		}
+	}
+	return nil
+}
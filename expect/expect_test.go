@@ -0,0 +1,90 @@
+package expect
+
+import (
+	"mrz.io/itermctl/iterm2"
+	"mrz.io/itermctl/screen"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func testScreen(y int64, lines ...string) *screen.Screen {
+	resp := &iterm2.GetBufferResponse{}
+
+	for i := range lines {
+		numCodePoints, repeats := int32(1), int32(len(lines[i]))
+		resp.Contents = append(resp.Contents, &iterm2.LineContents{
+			Text:              &lines[i],
+			CodePointsPerCell: []*iterm2.CodePointsPerCell{{NumCodePoints: &numCodePoints, Repeats: &repeats}},
+		})
+	}
+
+	return screen.NewAt(resp, y)
+}
+
+func TestMatch(t *testing.T) {
+	scr := testScreen(10, "$ ssh host", "Last login: today", "user@host:~$ ")
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(`(?i)password:`),
+		regexp.MustCompile(`(\w+)@(\w+):~\$ $`),
+		regexp.MustCompile(`(?m)^Last login: (\w+)$`),
+	}
+
+	m, end, ok := match(scr, screen.Coord{X: 10, Y: 10}, patterns)
+	if !ok {
+		t.Fatal("expected a match")
+	}
+
+	if m.Index != 2 || !reflect.DeepEqual(m.Groups, []string{"Last login: today", "today"}) {
+		t.Fatalf("expected the login line to match first, got %+v", m)
+	}
+
+	expectedRange := screen.CoordRange{Start: screen.Coord{X: 0, Y: 11}, End: screen.Coord{X: 17, Y: 11}}
+	if m.Range != expectedRange {
+		t.Fatalf("expected range %v, got %v", expectedRange, m.Range)
+	}
+
+	m, end, ok = match(scr, end, patterns)
+	if !ok {
+		t.Fatal("expected a second match")
+	}
+
+	if m.Index != 1 || !reflect.DeepEqual(m.Groups, []string{"user@host:~$ ", "user", "host"}) {
+		t.Fatalf("expected the shell prompt to match, got %+v", m)
+	}
+
+	if end != (screen.Coord{X: 13, Y: 12}) {
+		t.Fatalf("expected the end of the prompt, got %v", end)
+	}
+
+	if m, _, ok := match(scr, end, patterns); ok {
+		t.Fatalf("expected consumed output not to match again, got %+v", m)
+	}
+}
+
+func TestMatch_EarliestWins(t *testing.T) {
+	scr := testScreen(0, "foo bar", "")
+	patterns := []*regexp.Regexp{regexp.MustCompile(`bar`), regexp.MustCompile(`o+`), regexp.MustCompile(`fo`)}
+
+	m, _, ok := match(scr, screen.Coord{}, patterns)
+	if !ok || m.Index != 2 || m.Text != "fo" {
+		t.Fatalf("expected the earliest match, got %+v", m)
+	}
+}
+
+func TestMatch_IgnoresEmptyMatches(t *testing.T) {
+	scr := testScreen(0, "abc", "xx")
+
+	if m, _, ok := match(scr, screen.Coord{}, []*regexp.Regexp{regexp.MustCompile(`$`), regexp.MustCompile(`z*`)}); ok {
+		t.Fatalf("expected empty matches to be ignored, got %+v", m)
+	}
+
+	m, end, ok := match(scr, screen.Coord{}, []*regexp.Regexp{regexp.MustCompile(`x*`)})
+	if !ok || m.Text != "xx" {
+		t.Fatalf("expected the first non-empty match, got %+v", m)
+	}
+
+	if end != (screen.Coord{X: 2, Y: 1}) {
+		t.Fatalf("expected the match to consume output, got %v", end)
+	}
+}
@@ -0,0 +1,53 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"testing"
+)
+
+func TestApp_RestoreArrangement(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	name := test.AppName(t)
+
+	if err := app.SaveArrangement(name, testWindowResp.GetWindowId()); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := app.Arrangements()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, n := range names {
+		found = found || n == name
+	}
+
+	if !found {
+		t.Fatalf("expected arrangement %q in %v", name, names)
+	}
+
+	restored, err := app.RestoreArrangement(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(restored.WindowIds) != 1 || len(restored.SessionIds) != 1 {
+		t.Fatalf("expected 1 window and 1 session, got %v", restored)
+	}
+
+	if err := app.CloseTerminalWindow(true, restored.WindowIds...); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = app.RestoreArrangement("itermctl no such arrangement", "")
+	if !errors.Is(err, itermctl.ErrArrangementNotFound) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrArrangementNotFound, err)
+	}
+}
@@ -0,0 +1,105 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"io/ioutil"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestApp_SetBroadcastDomains(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+	newIds, err := app.Session(sessionId).SplitPane(true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, err := itermctl.MonitorBroadcastDomains(ctx, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	domain := itermctl.BroadcastDomain{sessionId, newIds[0]}
+	if err := app.SetBroadcastDomains(domain); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := app.SetBroadcastDomains(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	select {
+	case domains := <-changes:
+		if len(domains) != 1 || !domains[0].Contains(newIds[0]) {
+			t.Fatalf("expected %v, got %v", domain, domains)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for broadcast domains change")
+	}
+
+	domains, err := app.BroadcastDomains()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(domains) != 1 || !domains[0].Contains(sessionId) {
+		t.Fatalf("expected %v, got %v", domain, domains)
+	}
+
+	dir, err := ioutil.TempDir("", "itermctl-broadcast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// each session appends to a file named after its own ID
+	echo := func(text string) string {
+		return "echo " + text + " >> " + dir + "/${ITERM_SESSION_ID#*:}\n"
+	}
+
+	if err := app.SendTextTo(echo("to"), sessionId, newIds[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Session(sessionId).SendText(echo("suppressed"), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Session(sessionId).SendText(echo("broadcast"), true); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForFileContents(filepath.Join(dir, sessionId), "to\nsuppressed\nbroadcast\n", t)
+	waitForFileContents(filepath.Join(dir, newIds[0]), "to\nbroadcast\n", t)
+}
+
+func waitForFileContents(path string, expected string, t *testing.T) {
+	t.Helper()
+
+	var contents string
+
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			if contents = string(data); contents == expected {
+				return
+			}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Fatalf("expected %s to contain %q, got %q", path, expected, contents)
+}
@@ -0,0 +1,83 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"mrz.io/itermctl/expect"
+	"mrz.io/itermctl/internal/test"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestExpecter_Dialogue(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	e, err := expect.New(ctx, app.Session(testWindowResp.GetSessionId()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	if err := e.SendLine(`read -p "itermctl name? " n; echo "hello, $n!"`); err != nil {
+		t.Fatal(err)
+	}
+
+	match, err := e.Dialogue(ctx,
+		expect.Case{Pattern: regexp.MustCompile(`itermctl name\? $`), Reply: "expect"},
+		expect.Case{Pattern: regexp.MustCompile(`(?m)^hello, (\w+)!$`), Done: true},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if match.Groups[1] != "expect" {
+		t.Fatalf("expected the reply to be echoed, got %+v", match)
+	}
+
+	e.Timeout = 500 * time.Millisecond
+	if _, err := e.Expect(ctx, regexp.MustCompile(`hello, expect`)); !errors.Is(err, expect.ErrTimeout) {
+		t.Fatalf("expected %v, got %v", expect.ErrTimeout, err)
+	}
+
+	e.Timeout = 0
+	deadlineCtx, cancelDeadline := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancelDeadline()
+
+	_, err = e.Expect(deadlineCtx, regexp.MustCompile(`hello, expect`))
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, expect.ErrTimeout) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestExpect(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		if err := session.SendText("echo itermctl-$((6 * 7))\n", false); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	match, err := expect.Expect(ctx, session, regexp.MustCompile(`(?m)^itermctl-(\d+)$`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if match.Groups[1] != "42" {
+		t.Fatalf("expected the output of echo, got %+v", match)
+	}
+}
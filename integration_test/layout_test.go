@@ -0,0 +1,258 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"io/ioutil"
+	"math"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApp_ApplyLayout(t *testing.T) {
+	spec, err := itermctl.ParseLayout([]byte(`
+profile: itermctl test profile
+ready_timeout: 2s
+windows:
+  - name: main
+    tabs:
+      - root:
+          split: vertical
+          panes:
+            - name: left
+              size: 3
+            - name: right
+              directory: /tmp
+              variables:
+                user.itermctlTest: right
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := app.ApplyLayout(spec)
+	if ws != nil {
+		defer func() {
+			if err := ws.Close(true); err != nil {
+				t.Fatal(err)
+			}
+		}()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ws.SessionIds) != 2 || ws.WindowIds["main"] == "" {
+		t.Fatalf("unexpected workspace: %+v", ws)
+	}
+
+	var value string
+	if err := ws.Session("right").Variable("user.itermctlTest", &value); err != nil {
+		t.Fatal(err)
+	}
+
+	if value != "right" {
+		t.Fatalf("expected %q, got %q", "right", value)
+	}
+}
+
+func TestApp_ExportLayout(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	if _, err := app.Session(testWindowResp.GetSessionId()).SplitPane(true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := app.ExportLayout(testWindowResp.GetWindowId())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	root := spec.Windows[0].Tabs[0].Root
+	if root.Split != itermctl.SplitVertical || len(root.Panes) != 2 {
+		t.Fatalf("unexpected root pane: %+v", root)
+	}
+
+	if root.Panes[0].Size <= 0 || root.Panes[0].Profile == "" {
+		t.Fatalf("unexpected pane: %+v", root.Panes[0])
+	}
+}
+
+func TestApp_ApplyLayout_Nested(t *testing.T) {
+	dir, err := ioutil.TempDir("", "itermctl-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := itermctl.ParseLayout([]byte(fmt.Sprintf(`
+profile: itermctl test profile
+directory: %s
+ready_timeout: 5s
+windows:
+  - name: nested
+    tabs:
+      - root:
+          split: vertical
+          panes:
+            - name: left
+              size: 1
+              commands: ["pwd > left.out"]
+            - split: horizontal
+              size: 3
+              panes:
+                - name: top
+                  directory: sub
+                  commands: ["pwd > ../top.out"]
+                - name: bottom
+                  directory: "~"
+                  commands: ["pwd > %s/bottom.out"]
+`, dir, dir)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := app.ApplyLayout(spec)
+	if ws != nil {
+		defer func() {
+			if err := ws.Close(true); err != nil {
+				t.Fatal(err)
+			}
+		}()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ws.SessionIds) != 3 {
+		t.Fatalf("unexpected workspace: %+v", ws)
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"left.out":   dir,
+		"top.out":    filepath.Join(dir, "sub"),
+		"bottom.out": home,
+	}
+
+	for file, directory := range expected {
+		if pwd := waitForFile(filepath.Join(dir, file), t); !sameDirectory(pwd, directory) {
+			t.Fatalf("%s: expected %s, got %s", file, directory, pwd)
+		}
+	}
+
+	exported, err := app.ExportLayout(ws.WindowIds["nested"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkNestedLayout(exported, dir, t)
+
+	// applying the exported layout gives the same layout again
+	exported.ReadyTimeout = "5s"
+	exported.Windows[0].Name = "copy"
+
+	copied, err := app.ApplyLayout(exported)
+	if copied != nil {
+		defer func() {
+			if err := copied.Close(true); err != nil {
+				t.Fatal(err)
+			}
+		}()
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reexported, err := app.ExportLayout(copied.WindowIds["copy"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkNestedLayout(reexported, dir, t)
+}
+
+// checkNestedLayout checks the layout exported from the one applied by TestApp_ApplyLayout_Nested.
+func checkNestedLayout(spec *itermctl.LayoutSpec, dir string, t *testing.T) {
+	t.Helper()
+
+	if len(spec.Windows) != 1 || len(spec.Windows[0].Tabs) != 1 {
+		t.Fatalf("expected one window with one tab, got %+v", spec.Windows)
+	}
+
+	root := spec.Windows[0].Tabs[0].Root
+	if root.Split != itermctl.SplitVertical || len(root.Panes) != 2 {
+		t.Fatalf("unexpected root pane: %+v", root)
+	}
+
+	left, right := root.Panes[0], root.Panes[1]
+	if right.Split != itermctl.SplitHorizontal || len(right.Panes) != 2 {
+		t.Fatalf("unexpected right pane: %+v", right)
+	}
+
+	if ratio := right.Size / left.Size; math.Abs(ratio-3) > 0.5 {
+		t.Fatalf("expected the right pane to be 3 times as wide as the left one, got %v and %v", right.Size, left.Size)
+	}
+
+	directories := []string{left.Directory, right.Panes[0].Directory, right.Panes[1].Directory}
+	expected := []string{dir, filepath.Join(dir, "sub"), "~"}
+
+	for i := range directories {
+		if directories[i] != expected[i] && !sameDirectory(directories[i], expected[i]) {
+			t.Fatalf("expected directories %v, got %v", expected, directories)
+		}
+	}
+}
+
+func waitForFile(path string, t *testing.T) string {
+	t.Helper()
+
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); {
+		if data, err := ioutil.ReadFile(path); err == nil && len(data) > 0 {
+			return strings.TrimSpace(string(data))
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for %s", path)
+	return ""
+}
+
+// sameDirectory tells if two paths lead to the same directory, such as /tmp and /private/tmp on macOS, expanding "~".
+func sameDirectory(a, b string) bool {
+	resolve := func(path string) (string, error) {
+		path, err := homedir.Expand(path)
+		if err != nil {
+			return "", err
+		}
+		return filepath.EvalSymlinks(path)
+	}
+
+	a, errA := resolve(a)
+	b, errB := resolve(b)
+	return errA == nil && errB == nil && a == b
+}
@@ -0,0 +1,57 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonitorLocationChanges(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	locations, err := itermctl.MonitorLocationChanges(ctx, conn, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.TrackLocations(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Session(sessionId).SendText("cd /tmp\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	for location := range locations {
+		if location.SessionId != sessionId {
+			t.Fatalf("expected a location of %s, got %+v", sessionId, location)
+		}
+
+		if strings.HasSuffix(location.Directory, "/tmp") {
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("timeout waiting for the location change")
+	}
+
+	// the tracker may lag behind the monitor
+	time.Sleep(500 * time.Millisecond)
+
+	location, ok := app.Location(sessionId)
+	if !ok || !strings.HasSuffix(location.Directory, "/tmp") {
+		t.Fatalf("expected the tracked location to be /tmp, got %+v (%v)", location, ok)
+	}
+}
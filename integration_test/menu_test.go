@@ -0,0 +1,25 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"testing"
+)
+
+func TestApp_MenuItemState(t *testing.T) {
+	state, err := app.MenuItemState(itermctl.MenuNewWindow)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !state.Enabled {
+		t.Fatalf("expected %q to be enabled", itermctl.MenuNewWindow)
+	}
+
+	_, err = app.MenuItemState("itermctl no such menu item")
+	if !errors.Is(err, itermctl.ErrMenuItemBadIdentifier) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrMenuItemBadIdentifier, err)
+	}
+}
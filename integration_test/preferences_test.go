@@ -0,0 +1,45 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"testing"
+)
+
+func TestApp_DefaultProfile(t *testing.T) {
+	guid, err := app.DefaultProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.SetDefaultProfile(guid); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.SetDefaultProfile("not-a-guid"); !errors.Is(err, itermctl.ErrBadGuid) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrBadGuid, err)
+	}
+}
+
+func TestApp_Preferences(t *testing.T) {
+	results, err := app.Preferences("TabStyleWithAutomaticOption", "NoSuchPreference")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tabStyle int
+	if err := results[0].Value(&tabStyle); err != nil {
+		t.Fatal(err)
+	}
+
+	var missing interface{}
+	if err := results[1].Value(&missing); err != nil {
+		t.Fatal(err)
+	}
+
+	if missing != nil {
+		t.Fatalf("expected nil, got %v", missing)
+	}
+}
@@ -0,0 +1,112 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"testing"
+	"time"
+)
+
+func TestApp_ProfileByName(t *testing.T) {
+	profile, err := app.ProfileByName(profileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if profile == nil {
+		t.Fatalf("profile %q not found", profileName)
+	}
+
+	if profile.Guid == "" {
+		t.Fatalf("expected profile %q to have a GUID", profileName)
+	}
+}
+
+func TestSession_SetProfileProperties(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+	if session == nil {
+		t.Fatalf("no session: %s", testWindowResp.GetSessionId())
+	}
+
+	color := itermctl.NewColor(255, 0, 0)
+
+	err := session.SetProfileProperties(
+		itermctl.Assignment{Key: itermctl.ProfileBadgeText, Value: "itermctl"},
+		itermctl.Assignment{Key: itermctl.ProfileBadgeColor, Value: color},
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile, err := session.Profile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if profile.BadgeText != "itermctl" {
+		t.Fatalf("expected badge %q, got %q", "itermctl", profile.BadgeText)
+	}
+
+	if profile.BadgeColor == nil || profile.BadgeColor.Red != 1 {
+		t.Fatalf("expected badge color %v, got %v", color, profile.BadgeColor)
+	}
+}
+
+func TestMonitorProfileChanges(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := itermctl.MonitorProfileChanges(ctx, conn, ""); err == nil {
+		t.Fatal("expected an error for an empty GUID")
+	}
+
+	profile, err := app.ProfileByName(profileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := itermctl.MonitorProfileChanges(ctx, conn, profile.Guid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badge := itermctl.Assignment{Key: itermctl.ProfileBadgeText, Value: "itermctl-profile-change"}
+	if err := app.SetProfileProperties([]string{profile.Guid}, badge); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		restore := itermctl.Assignment{Key: itermctl.ProfileBadgeText, Value: profile.BadgeText}
+		if err := app.SetProfileProperties([]string{profile.Guid}, restore); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	var change itermctl.ProfileChange
+
+	select {
+	case change = <-changes:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for a profile change")
+	}
+
+	if change.Guid != profile.Guid {
+		t.Fatalf("expected a change of %s, got %s", profile.Guid, change.Guid)
+	}
+
+	changed, err := change.Profile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed.BadgeText != "itermctl-profile-change" {
+		t.Fatalf("expected badge %q, got %q", "itermctl-profile-change", changed.BadgeText)
+	}
+}
@@ -4,10 +4,12 @@ package integration_test
 
 import (
 	"context"
+	"errors"
 	"mrz.io/itermctl"
 	"mrz.io/itermctl/internal/test"
 	"mrz.io/itermctl/iterm2"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,3 +82,112 @@ func collectPrompts(src <-chan *iterm2.PromptNotification, sessionId string, max
 	}
 	return nil
 }
+
+func TestSession_Prompts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	promptNotifications, err := itermctl.MonitorPrompts(ctx, conn, testWindowResp.GetSessionId(),
+		iterm2.PromptMonitorMode_COMMAND_END)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	if err := session.SendText("echo itermctl-prompt\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	commandEnd := collectPrompts(promptNotifications, session.Id(), 1, t)[0]
+
+	prompt, err := session.Prompt(commandEnd.GetUniquePromptId())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prompt.Command != "echo itermctl-prompt" || prompt.State != itermctl.PromptFinished || prompt.ExitStatus != 0 {
+		t.Fatalf("unexpected prompt %+v", prompt)
+	}
+
+	output, err := prompt.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(output, "itermctl-prompt") {
+		t.Fatalf("expected the command's output, got %q", output)
+	}
+
+	prompts, err := session.Prompts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if findPromptById(prompts, prompt.Id) == nil {
+		t.Fatalf("expected prompt %s to be listed", prompt.Id)
+	}
+
+	last, err := session.LastPrompt()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if last.Id != prompts[len(prompts)-1].Id {
+		t.Fatalf("expected the last prompt to be %s, got %s", prompts[len(prompts)-1].Id, last.Id)
+	}
+}
+
+func findPromptById(prompts []*itermctl.Prompt, id string) *itermctl.Prompt {
+	for _, p := range prompts {
+		if p.Id == id {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func TestSession_Run(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := session.Run(ctx, "echo itermctl-run; false")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ExitStatus != 1 || !strings.Contains(result.Output, "itermctl-run") {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// text typed at the prompt is discarded
+	if err := session.SendText("itermctl-typed", false); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = session.Run(ctx, "echo itermctl-clean")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.ExitStatus != 0 || result.Prompt.Command != "echo itermctl-clean" {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	sleepCtx, cancelSleep := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancelSleep()
+
+	opts := itermctl.RunOptions{InterruptOnCancel: true}
+	if _, err := session.RunWithOptions(sleepCtx, "sleep 30", opts); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
@@ -0,0 +1,47 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamScreen(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	streamer, err := itermctl.StreamScreen(ctx, conn, sessionId, itermctl.ScreenStreamerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer streamer.Close()
+
+	first := <-streamer.Diffs()
+	if first == nil || len(first.ChangedRows) != len(first.Snapshot.Screen.Lines) {
+		t.Fatalf("expected the first diff to change all rows, got %+v", first)
+	}
+
+	if err := app.Session(sessionId).Inject([]byte("\r\nitermctl streamed\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	for diff := range streamer.Diffs() {
+		for _, row := range diff.ChangedRows {
+			if strings.Contains(diff.Snapshot.Screen.Lines[row].Text, "itermctl streamed") {
+				return
+			}
+		}
+	}
+
+	t.Fatalf("expected a diff with the injected line, stream ended with %v", streamer.Err())
+}
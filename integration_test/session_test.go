@@ -0,0 +1,137 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSession_Inject(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	if _, err := fmt.Fprint(session.OutputWriter(), "\r\n\x1b[1mitermctl injected\x1b[0m\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lines, err := session.TrailingLines(10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(itermctl.ToString(lines.GetContents()), "itermctl injected") {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for injected text")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func TestSession_SetSelection(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	if err := session.Inject([]byte("\r\nitermctl one\r\nitermctl two\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := session.TrailingLines(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	y := lines.GetCursor().GetY()
+	line := func(y int64) itermctl.SubSelection {
+		return itermctl.SubSelection{
+			Mode:  itermctl.SelectionCharacter,
+			Range: itermctl.CoordRange{Start: itermctl.Coord{X: 0, Y: y}, End: itermctl.Coord{X: 12, Y: y}},
+		}
+	}
+
+	sel := &itermctl.Selection{SubSelections: []itermctl.SubSelection{line(y - 2), line(y - 1)}}
+	if err := session.SetSelection(sel); err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := session.SelectedText()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text != "itermctl one\nitermctl two" {
+		t.Fatalf("expected the two injected lines, got %q", text)
+	}
+
+	if err := session.SetSelection(&itermctl.Selection{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSession_Scrollback(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	long := strings.Repeat("x", 500)
+	if err := session.Inject([]byte("\r\nitermctl first\r\n" + long + "\r\nitermctl last\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var forward []string
+	sb := session.Scrollback(ctx, itermctl.ScrollbackOptions{PageSize: 3})
+	for sb.Next() {
+		forward = append(forward, strings.TrimRight(sb.Line().Text, " "))
+	}
+
+	if err := sb.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsInOrder(forward, "itermctl first", long, "itermctl last") {
+		t.Fatalf("expected the injected lines in order, got %q", forward)
+	}
+
+	var reverse []string
+	sb = session.Scrollback(ctx, itermctl.ScrollbackOptions{PageSize: 3, Reverse: true})
+	for sb.Next() {
+		reverse = append(reverse, strings.TrimRight(sb.Line().Text, " "))
+	}
+
+	if err := sb.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsInOrder(reverse, "itermctl last", long, "itermctl first") {
+		t.Fatalf("expected the injected lines in reverse order, got %q", reverse)
+	}
+}
+
+func containsInOrder(lines []string, expected ...string) bool {
+	for _, line := range lines {
+		if len(expected) > 0 && line == expected[0] {
+			expected = expected[1:]
+		}
+	}
+
+	return len(expected) == 0
+}
@@ -0,0 +1,140 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"mrz.io/itermctl/iterm2"
+	"testing"
+	"time"
+)
+
+func TestSession_Restart(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+
+	err := session.Restart(true)
+	if !errors.Is(err, itermctl.ErrSessionNotRestartable) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrSessionNotRestartable, err)
+	}
+
+	if err := session.Restart(false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSupervise(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+	session := app.Session(sessionId)
+
+	prompts, err := itermctl.MonitorPrompts(ctx, conn, sessionId, iterm2.PromptMonitorMode_COMMAND_START)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supervisor, err := itermctl.Supervise(ctx, app, itermctl.SupervisorOptions{
+		InitialBackoff: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const command = "sleep 30"
+	supervisor.Watch(sessionId, command)
+
+	if err := session.SendText(command+"\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCommandStart(ctx, prompts, command, t)
+
+	if history := supervisor.History(sessionId); len(history) != 0 {
+		t.Fatalf("expected no restart of a running command, got %v", history)
+	}
+
+	// interrupt the command, which then exits with a non-zero status
+	if err := session.SendText("\x03", false); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCommandStart(ctx, prompts, command, t)
+
+	// the restart is recorded once iTerm2 acknowledged the command, which may happen after it started
+	history := supervisor.History(sessionId)
+	for i := 0; i < 10 && len(history) == 0; i++ {
+		time.Sleep(100 * time.Millisecond)
+		history = supervisor.History(sessionId)
+	}
+
+	if len(history) != 1 || !history[0].Restarted || history[0].ExitStatus == 0 || history[0].Err != nil {
+		t.Fatalf("expected one restart after a failure, got %+v", history)
+	}
+}
+
+func TestSupervise_AlreadyRunning(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+	session := app.Session(sessionId)
+
+	prompts, err := itermctl.MonitorPrompts(ctx, conn, sessionId, iterm2.PromptMonitorMode_COMMAND_START)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supervisor, err := itermctl.Supervise(ctx, app, itermctl.SupervisorOptions{
+		InitialBackoff: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const command = "sleep 30"
+
+	if err := session.SendText(command+"\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCommandStart(ctx, prompts, command, t)
+
+	// the command's start happened before it was watched
+	supervisor.Watch(sessionId, command)
+
+	if err := session.SendText("\x03", false); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForCommandStart(ctx, prompts, command, t)
+}
+
+func waitForCommandStart(ctx context.Context, prompts <-chan *iterm2.PromptNotification, command string, t *testing.T) {
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %q to start", command)
+		case n, ok := <-prompts:
+			if !ok {
+				t.Fatal("prompt notifications closed")
+			}
+
+			if n.GetCommandStart().GetCommand() == command {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"testing"
+)
+
+func TestTab_ResizePane(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+	if _, err := app.Session(sessionId).SplitPane(true, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tab := app.Tab(test.TabId(testWindowResp))
+
+	if err := tab.ApplyPreset(itermctl.EvenHorizontal); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tab.ResizePane(sessionId, itermctl.ResizeRight, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tab.ResizePane(sessionId, itermctl.ResizeLeft, 5); !errors.Is(err, itermctl.ErrNoAdjacentPane) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrNoAdjacentPane, err)
+	}
+
+	if err := tab.SetProportions(1, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tab.ApplyPreset(itermctl.EvenVertical); !errors.Is(err, itermctl.ErrIncompatibleLayout) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrIncompatibleLayout, err)
+	}
+}
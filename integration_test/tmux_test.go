@@ -0,0 +1,119 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"errors"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApp_TmuxConnections(t *testing.T) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+
+	if err := app.Session(sessionId).SendText("tmux -CC -L itermctl-test new-session -s itermctl-test\n", false); err != nil {
+		t.Fatal(err)
+	}
+
+	tmux := waitForTmuxConnection(sessionId, t)
+
+	defer func() {
+		if _, err := tmux.Command(ctx, "kill-server"); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	if tmux.OwningSession().Id() != sessionId {
+		t.Fatalf("expected owning session %s, got %s", sessionId, tmux.OwningSession().Id())
+	}
+
+	lines, err := tmux.Command(ctx, "display-message -p '#{session_name}'")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(lines, []string{"itermctl-test"}) {
+		t.Fatalf("expected the name of the tmux session, got %q", lines)
+	}
+
+	if _, err := tmux.Command(ctx, "no-such-command"); !errors.Is(err, itermctl.ErrTmuxCommandFailed) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrTmuxCommandFailed, err)
+	}
+
+	windows, err := tmux.Windows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windows) != 1 || windows[0].TabId == "" {
+		t.Fatalf("expected one visible tmux window, got %+v", windows)
+	}
+
+	tab, err := tmux.CreateWindow(windows[0].Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err = tmux.Windows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windows) != 2 || windows[1].TabId != tab.Id() {
+		t.Fatalf("expected a second tmux window shown in tab %s, got %+v", tab.Id(), windows)
+	}
+
+	if err := tmux.SetWindowVisible(windows[1].Id, false); err != nil {
+		t.Fatal(err)
+	}
+
+	windows, err = tmux.Windows(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(windows) != 2 || windows[1].TabId != "" {
+		t.Fatalf("expected the second tmux window to be hidden, got %+v", windows)
+	}
+
+	if err := tmux.SetWindowVisible("no-such-window", true); !errors.Is(err, itermctl.ErrTmuxInvalidWindowId) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrTmuxInvalidWindowId, err)
+	}
+}
+
+func waitForTmuxConnection(sessionId string, t *testing.T) *itermctl.TmuxConnection {
+	deadline := time.Now().Add(10 * time.Second)
+
+	for time.Now().Before(deadline) {
+		connections, err := app.TmuxConnections()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range connections {
+			if c.OwningSessionId == sessionId {
+				return c
+			}
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Fatalf("timed out waiting for a tmux connection in session %s", sessionId)
+	return nil
+}
@@ -0,0 +1,115 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"context"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"mrz.io/itermctl/iterm2"
+	"testing"
+	"time"
+)
+
+func TestMonitorVariable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	sessionId := testWindowResp.GetSessionId()
+	session := app.SessionById(sessionId)
+
+	if _, err := itermctl.MonitorVariable(ctx, conn, iterm2.VariableScope_SESSION, "no-such-session", "jobName"); err == nil {
+		t.Fatal("expected an error for an unknown session")
+	}
+
+	changes, err := itermctl.MonitorVariable(ctx, conn, iterm2.VariableScope_SESSION, sessionId, "user.itermctlMonitor")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"foo", "bar"} {
+		if err := session.SetVariable("user.itermctlMonitor", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, expected := range [][2]string{{"", "foo"}, {"foo", "bar"}} {
+		change := waitForVariableChange(ctx, changes, sessionId, "user.itermctlMonitor", t)
+
+		oldValue, newValue, err := change.Strings()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if oldValue != expected[0] || newValue != expected[1] {
+			t.Fatalf("expected %q -> %q, got %q -> %q", expected[0], expected[1], oldValue, newValue)
+		}
+	}
+}
+
+func TestWatchVariables(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watcher, err := itermctl.WatchVariables(ctx, app, "user.itermctlWatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	sessionId := testWindowResp.GetSessionId()
+
+	// a new session is announced with its initial value
+	waitForVariableChange(ctx, watcher.Changes(), sessionId, "user.itermctlWatch", t)
+
+	if err := app.SessionById(sessionId).SetVariable("user.itermctlWatch", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	change := waitForVariableChange(ctx, watcher.Changes(), sessionId, "user.itermctlWatch", t)
+	if _, newValue, err := change.Strings(); err != nil || newValue != "foo" {
+		t.Fatalf("expected %q, got %q (%v)", "foo", newValue, err)
+	}
+
+	if value, ok := watcher.Value(sessionId, "user.itermctlWatch"); !ok || value != "foo" {
+		t.Fatalf("expected %q, got %q (%t)", "foo", value, ok)
+	}
+
+	closeTestWindow()
+
+	// terminated sessions are forgotten
+	for {
+		if _, ok := watcher.Value(sessionId, "user.itermctlWatch"); !ok {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			t.Fatalf("expected session %s to be forgotten", sessionId)
+		case <-watcher.Changes():
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func waitForVariableChange(ctx context.Context, changes <-chan itermctl.VariableChange, identifier, name string,
+	t *testing.T) itermctl.VariableChange {
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for a change of %s in %s", name, identifier)
+		case change, ok := <-changes:
+			if !ok {
+				t.Fatal("variable changes closed")
+			}
+
+			if change.Identifier == identifier && change.Name == name {
+				return change
+			}
+		}
+	}
+}
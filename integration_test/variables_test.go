@@ -0,0 +1,64 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"mrz.io/itermctl/iterm2"
+	"testing"
+)
+
+func TestSession_SetVariable(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	session := app.Session(testWindowResp.GetSessionId())
+	if session == nil {
+		t.Fatalf("no session: %s", testWindowResp.GetSessionId())
+	}
+
+	if err := session.SetVariable("user.itermctlTest", "foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	var value string
+	if err := session.Variable("user.itermctlTest", &value); err != nil {
+		t.Fatal(err)
+	}
+
+	if value != "foo" {
+		t.Fatalf("expected %q, got %q", "foo", value)
+	}
+
+	variables, err := session.Variables()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := variables["user"]; !ok {
+		t.Fatalf("expected user variables, got %v", variables)
+	}
+}
+
+func TestSetVariables_AllTabs(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	err := itermctl.SetVariables(conn, iterm2.VariableScope_TAB, itermctl.AllSessions, map[string]interface{}{
+		"user.itermctlTest": 42,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value int
+	if err := app.Tab(test.TabId(testWindowResp)).Variable("user.itermctlTest", &value); err != nil {
+		t.Fatal(err)
+	}
+
+	if value != 42 {
+		t.Fatalf("expected 42, got %d", value)
+	}
+}
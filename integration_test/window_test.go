@@ -0,0 +1,72 @@
+// +build test_with_iterm
+
+package integration_test
+
+import (
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/internal/test"
+	"reflect"
+	"testing"
+)
+
+func TestWindow_ReorderTabs(t *testing.T) {
+	testWindowResp, closeTestWindow := test.CreateWindow(app, t)
+	defer closeTestWindow()
+
+	window := app.Window(testWindowResp.GetWindowId())
+	first := test.TabId(testWindowResp)
+
+	if _, err := app.CreateTab(window.Id(), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tabIds, err := window.TabIds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := tabIds[1]
+
+	if err := window.ReorderTabs(second); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTabIds(t, window, second, first)
+
+	if err := app.MoveTab(second, window.Id(), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTabIds(t, window, first, second)
+
+	if err := app.Tab(second).SetVariable("user.sortKey", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := app.Tab(first).SetVariable("user.sortKey", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	byUserKey := func(tab *itermctl.Tab) (itermctl.TabKey, error) {
+		var key string
+		err := tab.Variable("user.sortKey", &key)
+		return itermctl.StringKey(key), err
+	}
+
+	if err := window.SortTabs(byUserKey, false); err != nil {
+		t.Fatal(err)
+	}
+
+	assertTabIds(t, window, second, first)
+}
+
+func assertTabIds(t *testing.T, window *itermctl.Window, expected ...string) {
+	tabIds, err := window.TabIds()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(tabIds, expected) {
+		t.Fatalf("expected tabs %v, got %v", expected, tabIds)
+	}
+}
@@ -25,6 +25,15 @@ func UnmarshalString(v string, target interface{}) error {
 	return nil
 }
 
+func MarshalString(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshal string: %w", err)
+	}
+
+	return string(data), nil
+}
+
 func MustMarshal(v interface{}) string {
 	data, err := json.Marshal(v)
 	if err != nil {
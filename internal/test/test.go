@@ -68,3 +68,8 @@ func CreateWindow(app *itermctl.App, t *testing.T) (*iterm2.CreateTabResponse, f
 		delete(windows, t.Name())
 	}
 }
+
+// TabId returns the ID of the tab created with CreateWindow, as a string.
+func TabId(resp *iterm2.CreateTabResponse) string {
+	return fmt.Sprintf("%d", resp.GetTabId())
+}
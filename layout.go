@@ -0,0 +1,694 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"mrz.io/itermctl/iterm2"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SplitVertical splits a pane with a vertical divider, laying its children side by side.
+	SplitVertical = "vertical"
+	// SplitHorizontal splits a pane with a horizontal divider, stacking its children.
+	SplitHorizontal = "horizontal"
+
+	// DefaultReadyTimeout is how long ApplyLayout waits for a shell prompt before sending startup commands anyway.
+	DefaultReadyTimeout = 5 * time.Second
+)
+
+// LayoutSpec describes a workspace: windows, their tabs, and each tab's tree of split panes. The profile and directory
+// given at any level are inherited by the panes below, where they can be overridden; relative directories are
+// relative to the inherited one. Parse one from YAML or JSON with ParseLayout, and realise it with App.ApplyLayout.
+type LayoutSpec struct {
+	Profile   string `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Directory string `yaml:"directory,omitempty" json:"directory,omitempty"`
+	// ReadyTimeout is a duration such as "10s", DefaultReadyTimeout if empty.
+	ReadyTimeout string        `yaml:"ready_timeout,omitempty" json:"ready_timeout,omitempty"`
+	Windows      []*WindowSpec `yaml:"windows" json:"windows"`
+}
+
+// WindowSpec describes a window of a LayoutSpec.
+type WindowSpec struct {
+	Name      string     `yaml:"name,omitempty" json:"name,omitempty"`
+	Profile   string     `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Directory string     `yaml:"directory,omitempty" json:"directory,omitempty"`
+	Tabs      []*TabSpec `yaml:"tabs" json:"tabs"`
+}
+
+// TabSpec describes a tab of a WindowSpec. Root is the tab's only pane, or the split holding all of them.
+type TabSpec struct {
+	Name      string    `yaml:"name,omitempty" json:"name,omitempty"`
+	Profile   string    `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Directory string    `yaml:"directory,omitempty" json:"directory,omitempty"`
+	Root      *PaneSpec `yaml:"root" json:"root"`
+}
+
+// PaneSpec is either a pane running a session, or a split (Split is SplitVertical or SplitHorizontal) of the Panes
+// below it. Size is the pane's share of its parent split, relative to its siblings; 0 counts as 1. Properties
+// customize the session's profile (see Assignment), Commands are sent to the session once its shell is ready, and
+// Variables are user variables set on the session.
+type PaneSpec struct {
+	Name       string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Split      string                 `yaml:"split,omitempty" json:"split,omitempty"`
+	Panes      []*PaneSpec            `yaml:"panes,omitempty" json:"panes,omitempty"`
+	Size       float64                `yaml:"size,omitempty" json:"size,omitempty"`
+	Profile    string                 `yaml:"profile,omitempty" json:"profile,omitempty"`
+	Directory  string                 `yaml:"directory,omitempty" json:"directory,omitempty"`
+	Properties map[string]interface{} `yaml:"properties,omitempty" json:"properties,omitempty"`
+	Commands   []string               `yaml:"commands,omitempty" json:"commands,omitempty"`
+	Variables  map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+}
+
+// ParseLayout parses and validates a LayoutSpec written in YAML or JSON.
+func ParseLayout(data []byte) (*LayoutSpec, error) {
+	spec := &LayoutSpec{}
+
+	if err := yaml.Unmarshal(data, spec); err != nil {
+		return nil, fmt.Errorf("parse layout: %w", err)
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// Validate checks that the spec can be realised: every window has tabs, every tab a root pane, splits have panes and
+// a valid direction, sizes are not negative, and window, tab and pane names are unique.
+func (spec *LayoutSpec) Validate() error {
+	if spec.ReadyTimeout != "" {
+		if _, err := time.ParseDuration(spec.ReadyTimeout); err != nil {
+			return fmt.Errorf("layout: ready_timeout: %w", err)
+		}
+	}
+
+	if len(spec.Windows) == 0 {
+		return fmt.Errorf("layout: no windows")
+	}
+
+	names := make(map[string]bool)
+
+	checkName := func(kind, name string) error {
+		if name == "" {
+			return nil
+		}
+
+		if names[kind+name] {
+			return fmt.Errorf("layout: duplicate %s name %q", kind, name)
+		}
+
+		names[kind+name] = true
+		return nil
+	}
+
+	var checkPane func(pane *PaneSpec) error
+	checkPane = func(pane *PaneSpec) error {
+		if err := checkName("pane", pane.Name); err != nil {
+			return err
+		}
+
+		if pane.Size < 0 {
+			return fmt.Errorf("layout: pane %q: negative size", pane.Name)
+		}
+
+		if pane.Split == "" {
+			if len(pane.Panes) > 0 {
+				return fmt.Errorf("layout: pane %q: panes without split direction", pane.Name)
+			}
+			return nil
+		}
+
+		if pane.Split != SplitVertical && pane.Split != SplitHorizontal {
+			return fmt.Errorf("layout: pane %q: unknown split direction %q", pane.Name, pane.Split)
+		}
+
+		if len(pane.Panes) == 0 {
+			return fmt.Errorf("layout: pane %q: split without panes", pane.Name)
+		}
+
+		if len(pane.Commands) > 0 || len(pane.Variables) > 0 {
+			return fmt.Errorf("layout: pane %q: a split cannot have commands nor variables", pane.Name)
+		}
+
+		for _, child := range pane.Panes {
+			if child == nil {
+				return fmt.Errorf("layout: pane %q: empty pane", pane.Name)
+			}
+
+			if err := checkPane(child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, win := range spec.Windows {
+		if err := checkName("window", win.Name); err != nil {
+			return err
+		}
+
+		if len(win.Tabs) == 0 {
+			return fmt.Errorf("layout: window %q: no tabs", win.Name)
+		}
+
+		for _, tab := range win.Tabs {
+			if err := checkName("tab", tab.Name); err != nil {
+				return err
+			}
+
+			if tab.Root == nil {
+				return fmt.Errorf("layout: tab %q: no root pane", tab.Name)
+			}
+
+			if err := checkPane(tab.Root); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Workspace is a LayoutSpec realised by App.ApplyLayout. It maps the names given in the spec to the IDs of the
+// windows, tabs and sessions that were created; unnamed ones are not mapped.
+type Workspace struct {
+	WindowIds  map[string]string
+	TabIds     map[string]string
+	SessionIds map[string]string
+
+	app     *App
+	windows []string
+}
+
+// Session returns the session created for the named pane, or nil if there's no such pane.
+func (w *Workspace) Session(name string) *Session {
+	id, ok := w.SessionIds[name]
+	if !ok {
+		return nil
+	}
+
+	return newSession(id, w.app, w.app.conn, false)
+}
+
+// Close closes all the windows of the workspace.
+func (w *Workspace) Close(force bool) error {
+	if len(w.windows) == 0 {
+		return nil
+	}
+
+	return w.app.CloseTerminalWindow(force, w.windows...)
+}
+
+// ApplyLayout realises a LayoutSpec: it creates its windows, tabs and split panes, resizes the panes, sets their user
+// variables, and sends the startup commands once each shell shows its first prompt, or after the spec's ReadyTimeout
+// (prompts are detected only when shell integration is installed). When an error occurs, the returned Workspace holds
+// what was created so far, so that it can be closed.
+func (a *App) ApplyLayout(spec *LayoutSpec) (*Workspace, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	readyTimeout := DefaultReadyTimeout
+	if spec.ReadyTimeout != "" {
+		readyTimeout, _ = time.ParseDuration(spec.ReadyTimeout)
+	}
+
+	ws := &Workspace{
+		WindowIds:  make(map[string]string),
+		TabIds:     make(map[string]string),
+		SessionIds: make(map[string]string),
+		app:        a,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ready, err := monitorReadySessions(ctx, a.conn)
+	if err != nil {
+		return nil, fmt.Errorf("apply layout: %w", err)
+	}
+
+	var panes []*layoutPane
+
+	for _, winSpec := range spec.Windows {
+		windowId := ""
+
+		for i, tabSpec := range winSpec.Tabs {
+			defaults := layoutDefaults{profile: spec.Profile, directory: spec.Directory}
+			defaults = defaults.with(winSpec.Profile, winSpec.Directory).with(tabSpec.Profile, tabSpec.Directory)
+			root := newLayoutPane(tabSpec.Root, defaults)
+
+			first := root.firstLeaf()
+			resp, err := a.CreateTabWithProfile(windowId, uint32(i), first.profile, first.customizations()...)
+			if err != nil {
+				return ws, fmt.Errorf("apply layout: %w", err)
+			}
+
+			if windowId == "" {
+				windowId = resp.GetWindowId()
+				ws.windows = append(ws.windows, windowId)
+				if winSpec.Name != "" {
+					ws.WindowIds[winSpec.Name] = windowId
+				}
+			}
+
+			tab := a.Tab(fmt.Sprintf("%d", resp.GetTabId()))
+			if tabSpec.Name != "" {
+				ws.TabIds[tabSpec.Name] = tab.Id()
+			}
+
+			if err := a.splitLayoutPane(root, resp.GetSessionId()); err != nil {
+				return ws, fmt.Errorf("apply layout: tab %q: %w", tabSpec.Name, err)
+			}
+
+			if err := resizeLayoutPanes(tab, root); err != nil {
+				return ws, fmt.Errorf("apply layout: tab %q: %w", tabSpec.Name, err)
+			}
+
+			panes = append(panes, root.leaves()...)
+		}
+	}
+
+	for _, pane := range panes {
+		if pane.spec.Name != "" {
+			ws.SessionIds[pane.spec.Name] = pane.sessionId
+		}
+
+		if len(pane.spec.Variables) > 0 {
+			err := SetVariables(a.conn, iterm2.VariableScope_SESSION, pane.sessionId, pane.spec.Variables)
+			if err != nil {
+				return ws, fmt.Errorf("apply layout: pane %q: %w", pane.spec.Name, err)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(readyTimeout)
+
+	for _, pane := range panes {
+		if len(pane.spec.Commands) == 0 {
+			continue
+		}
+
+		if !ready.wait(pane.sessionId, deadline) {
+			logrus.Debugf("apply layout: %s: no prompt before timeout", pane.sessionId)
+		}
+
+		session := newSession(pane.sessionId, a, a.conn, false)
+		for _, command := range pane.spec.Commands {
+			if err := session.SendText(command+"\n", false); err != nil {
+				return ws, fmt.Errorf("apply layout: pane %q: %w", pane.spec.Name, err)
+			}
+		}
+	}
+
+	return ws, nil
+}
+
+// splitLayoutPane splits the session occupying a pane until each leaf below it has its own session. Splits are
+// realised in order, always splitting the last session, so that iTerm2 adds the new sessions to the same node.
+func (a *App) splitLayoutPane(pane *layoutPane, sessionId string) error {
+	if len(pane.children) == 0 {
+		pane.sessionId = sessionId
+		return nil
+	}
+
+	sessionIds := []string{sessionId}
+
+	for _, child := range pane.children[1:] {
+		last := newSession(sessionIds[len(sessionIds)-1], a, a.conn, false)
+		first := child.firstLeaf()
+
+		ids, err := last.SplitPaneWithProfile(pane.vertical, false, first.profile, first.customizations()...)
+		if err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return fmt.Errorf("split pane: no session created")
+		}
+
+		sessionIds = append(sessionIds, ids[0])
+	}
+
+	for i, child := range pane.children {
+		if err := a.splitLayoutPane(child, sessionIds[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resizeLayoutPanes applies the sizes of the panes to the tab's split tree.
+func resizeLayoutPanes(tab *Tab, root *layoutPane) error {
+	if len(root.children) == 0 {
+		return nil
+	}
+
+	tree, err := tab.splitTree()
+	if err != nil {
+		return err
+	}
+
+	if err := applyLayoutSizes(tree, root); err != nil {
+		return err
+	}
+
+	return tab.setSplitTree(tree)
+}
+
+func applyLayoutSizes(node *iterm2.SplitTreeNode, pane *layoutPane) error {
+	if node.GetVertical() != pane.vertical || len(node.GetLinks()) != len(pane.children) {
+		return fmt.Errorf("split tree does not match the layout")
+	}
+
+	var weights []float64
+
+	for i, child := range pane.children {
+		link := node.GetLinks()[i]
+		weights = append(weights, child.size)
+
+		if len(child.children) == 0 {
+			if link.GetSession().GetUniqueIdentifier() != child.sessionId {
+				return fmt.Errorf("split tree does not match the layout")
+			}
+			continue
+		}
+
+		if link.GetNode() == nil {
+			return fmt.Errorf("split tree does not match the layout")
+		}
+	}
+
+	resizeNode(node, weights)
+
+	for i, child := range pane.children {
+		if len(child.children) > 0 {
+			if err := applyLayoutSizes(node.GetLinks()[i].GetNode(), child); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type layoutDefaults struct {
+	profile   string
+	directory string
+}
+
+func (d layoutDefaults) with(profile, directory string) layoutDefaults {
+	if profile != "" {
+		d.profile = profile
+	}
+
+	if directory != "" {
+		// paths relative to the home directory are expanded when the pane is created
+		if filepath.IsAbs(directory) || strings.HasPrefix(directory, "~") || d.directory == "" {
+			d.directory = directory
+		} else {
+			d.directory = filepath.Join(d.directory, directory)
+		}
+	}
+
+	return d
+}
+
+// layoutPane is a PaneSpec with inherited settings resolved, and with nested splits in the same direction merged
+// into their parent, like iTerm2 does.
+type layoutPane struct {
+	spec      *PaneSpec
+	profile   string
+	directory string
+	size      float64
+	vertical  bool
+	children  []*layoutPane
+	sessionId string
+}
+
+func newLayoutPane(spec *PaneSpec, defaults layoutDefaults) *layoutPane {
+	defaults = defaults.with(spec.Profile, spec.Directory)
+
+	pane := &layoutPane{
+		spec:      spec,
+		profile:   defaults.profile,
+		directory: defaults.directory,
+		size:      spec.Size,
+		vertical:  spec.Split == SplitVertical,
+	}
+
+	if pane.size == 0 {
+		pane.size = 1
+	}
+
+	if len(spec.Panes) == 1 {
+		child := newLayoutPane(spec.Panes[0], defaults)
+		child.size = pane.size
+		return child
+	}
+
+	for _, childSpec := range spec.Panes {
+		child := newLayoutPane(childSpec, defaults)
+
+		if len(child.children) == 0 || child.vertical != pane.vertical {
+			pane.children = append(pane.children, child)
+			continue
+		}
+
+		total := 0.0
+		for _, grandchild := range child.children {
+			total += grandchild.size
+		}
+
+		for _, grandchild := range child.children {
+			grandchild.size = child.size * grandchild.size / total
+			pane.children = append(pane.children, grandchild)
+		}
+	}
+
+	return pane
+}
+
+func (p *layoutPane) firstLeaf() *layoutPane {
+	if len(p.children) == 0 {
+		return p
+	}
+	return p.children[0].firstLeaf()
+}
+
+func (p *layoutPane) leaves() []*layoutPane {
+	if len(p.children) == 0 {
+		return []*layoutPane{p}
+	}
+
+	var leaves []*layoutPane
+	for _, child := range p.children {
+		leaves = append(leaves, child.leaves()...)
+	}
+
+	return leaves
+}
+
+// customizations returns the profile customizations of the pane's session, sorted by key.
+func (p *layoutPane) customizations() []Assignment {
+	var assignments []Assignment
+
+	for key, value := range p.spec.Properties {
+		assignments = append(assignments, Assignment{Key: key, Value: value})
+	}
+
+	sort.Slice(assignments, func(i, j int) bool {
+		return assignments[i].Key < assignments[j].Key
+	})
+
+	if p.directory != "" {
+		directory, err := homedir.Expand(p.directory)
+		if err != nil {
+			directory = p.directory
+		}
+
+		assignments = append(assignments,
+			Assignment{Key: ProfileCustomDirectory, Value: "Yes"},
+			Assignment{Key: ProfileWorkingDirectory, Value: directory},
+		)
+	}
+
+	return assignments
+}
+
+// readySessions records the sessions that have shown a prompt.
+type readySessions struct {
+	mx    *sync.Mutex
+	ready map[string]chan struct{}
+}
+
+func monitorReadySessions(ctx context.Context, conn *Connection) (*readySessions, error) {
+	prompts, err := MonitorPrompts(ctx, conn, AllSessions, iterm2.PromptMonitorMode_PROMPT)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &readySessions{mx: &sync.Mutex{}, ready: make(map[string]chan struct{})}
+
+	go func() {
+		for n := range prompts {
+			r.markReady(n.GetSession())
+		}
+	}()
+
+	return r, nil
+}
+
+func (r *readySessions) ch(sessionId string) chan struct{} {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.ready[sessionId]; !ok {
+		r.ready[sessionId] = make(chan struct{})
+	}
+
+	return r.ready[sessionId]
+}
+
+func (r *readySessions) markReady(sessionId string) {
+	ch := r.ch(sessionId)
+
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	select {
+	case <-ch:
+	default:
+		close(ch)
+	}
+}
+
+// wait blocks until the session has shown a prompt, returning false if the deadline passes first.
+func (r *readySessions) wait(sessionId string, deadline time.Time) bool {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-r.ch(sessionId):
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// shells are the job names ExportLayout considers idle, not worth a startup command.
+var shells = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "fish": true, "ksh": true, "tcsh": true, "csh": true, "dash": true,
+	"login": true,
+}
+
+// ExportLayout captures the window with the given ID, or all windows if windowId is empty, as a LayoutSpec that can be
+// serialised to YAML or JSON and applied later with ApplyLayout. Pane sizes are the panes' grid sizes; each pane gets
+// its session's profile name and current directory, and its foreground job as startup command unless it's a shell.
+// Directories under the home directory are written relative to "~", so that the spec can be used by another user.
+// Directories and job names are known only when shell integration is installed.
+func (a *App) ExportLayout(windowId string) (*LayoutSpec, error) {
+	resp, err := a.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("export layout: %w", err)
+	}
+
+	spec := &LayoutSpec{}
+
+	for _, win := range resp.GetWindows() {
+		if windowId != "" && win.GetWindowId() != windowId {
+			continue
+		}
+
+		winSpec := &WindowSpec{}
+
+		for _, tab := range win.GetTabs() {
+			root, err := a.exportSplitTree(tab.GetRoot())
+			if err != nil {
+				return nil, fmt.Errorf("export layout: %w", err)
+			}
+
+			winSpec.Tabs = append(winSpec.Tabs, &TabSpec{Root: root})
+		}
+
+		spec.Windows = append(spec.Windows, winSpec)
+	}
+
+	if windowId != "" && len(spec.Windows) == 0 {
+		return nil, fmt.Errorf("export layout: window %q not found", windowId)
+	}
+
+	return spec, nil
+}
+
+func (a *App) exportSplitTree(node *iterm2.SplitTreeNode) (*PaneSpec, error) {
+	if len(node.GetLinks()) == 1 {
+		return a.exportSplitTreeLink(node.GetLinks()[0])
+	}
+
+	pane := &PaneSpec{Split: SplitHorizontal}
+	if node.GetVertical() {
+		pane.Split = SplitVertical
+	}
+
+	for _, link := range node.GetLinks() {
+		child, err := a.exportSplitTreeLink(link)
+		if err != nil {
+			return nil, err
+		}
+
+		child.Size = float64(linkExtent(link, node.GetVertical()))
+		pane.Panes = append(pane.Panes, child)
+	}
+
+	return pane, nil
+}
+
+func (a *App) exportSplitTreeLink(link *iterm2.SplitTreeNode_SplitTreeLink) (*PaneSpec, error) {
+	if link.GetNode() != nil {
+		return a.exportSplitTree(link.GetNode())
+	}
+
+	session := newSession(link.GetSession().GetUniqueIdentifier(), a, a.conn, false)
+
+	values, err := session.getVariables("profileName", "path", "jobName")
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", session.Id(), err)
+	}
+
+	var decoded []string
+	for _, value := range values {
+		s, err := variableString(value)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", session.Id(), err)
+		}
+		decoded = append(decoded, s)
+	}
+
+	pane := &PaneSpec{Profile: decoded[0], Directory: decoded[1]}
+
+	if home, err := homedir.Dir(); err == nil && home != "" {
+		if pane.Directory == home {
+			pane.Directory = "~"
+		} else if strings.HasPrefix(pane.Directory, home+"/") {
+			pane.Directory = "~" + strings.TrimPrefix(pane.Directory, home)
+		}
+	}
+
+	if job := decoded[2]; job != "" && !shells[filepath.Base(job)] {
+		pane.Commands = []string{job}
+	}
+
+	return pane, nil
+}
@@ -0,0 +1,108 @@
+package itermctl
+
+import (
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"testing"
+)
+
+func TestNewLayoutPane(t *testing.T) {
+	spec := &PaneSpec{
+		Split: SplitVertical,
+		Panes: []*PaneSpec{
+			{Name: "a", Directory: "a"},
+			{Split: SplitVertical, Size: 2, Directory: "b", Panes: []*PaneSpec{
+				{Name: "c", Directory: "~/c", Profile: "other"},
+				{Name: "d", Size: 3},
+			}},
+			{Split: SplitHorizontal, Panes: []*PaneSpec{
+				{Name: "e", Directory: "/e"},
+				{Name: "f"},
+			}},
+		},
+	}
+
+	root := newLayoutPane(spec, layoutDefaults{profile: "default", directory: "/base"})
+
+	type leaf struct {
+		name, profile, directory string
+		size                     float64
+	}
+
+	var leaves []leaf
+	for _, p := range root.leaves() {
+		leaves = append(leaves, leaf{p.spec.Name, p.profile, p.directory, p.size})
+	}
+
+	expected := []leaf{
+		{"a", "default", "/base/a", 1},
+		{"c", "other", "~/c", 0.5},
+		{"d", "default", "/base/b", 1.5},
+		{"e", "default", "/e", 1},
+		{"f", "default", "/base", 1},
+	}
+
+	if !reflect.DeepEqual(leaves, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, leaves)
+	}
+
+	// the nested vertical split is merged into the root, the horizontal one isn't
+	if len(root.children) != 4 || !root.vertical || root.children[3].vertical {
+		t.Fatalf("unexpected tree: %+v", root)
+	}
+
+	if c := root.children[1].customizations(); len(c) != 2 || c[1].Value == "~/c" {
+		t.Fatalf("expected the directory to be expanded, got %+v", c)
+	}
+}
+
+func TestApplyLayoutSizes(t *testing.T) {
+	root := newLayoutPane(&PaneSpec{
+		Split: SplitVertical,
+		Panes: []*PaneSpec{
+			{Size: 1},
+			{Size: 3, Split: SplitHorizontal, Panes: []*PaneSpec{{Size: 1}, {Size: 1}}},
+		},
+	}, layoutDefaults{})
+
+	for i, leaf := range root.leaves() {
+		leaf.sessionId = string(rune('a' + i))
+	}
+
+	session := func(id string, width, height int32) *iterm2.SplitTreeNode_SplitTreeLink {
+		return &iterm2.SplitTreeNode_SplitTreeLink{Child: &iterm2.SplitTreeNode_SplitTreeLink_Session{
+			Session: &iterm2.SessionSummary{
+				UniqueIdentifier: &id,
+				GridSize:         &iterm2.Size{Width: &width, Height: &height},
+			},
+		}}
+	}
+
+	vertical, horizontal := true, false
+	tree := &iterm2.SplitTreeNode{Vertical: &vertical, Links: []*iterm2.SplitTreeNode_SplitTreeLink{
+		session("a", 51, 41),
+		{Child: &iterm2.SplitTreeNode_SplitTreeLink_Node{Node: &iterm2.SplitTreeNode{
+			Vertical: &horizontal,
+			Links:    []*iterm2.SplitTreeNode_SplitTreeLink{session("b", 51, 30), session("c", 51, 10)},
+		}}},
+	}}
+
+	if err := applyLayoutSizes(tree, root); err != nil {
+		t.Fatal(err)
+	}
+
+	var sizes [][2]int32
+	for _, s := range splitTreeSessions(tree) {
+		sizes = append(sizes, [2]int32{s.GetGridSize().GetWidth(), s.GetGridSize().GetHeight()})
+	}
+
+	expected := [][2]int32{{26, 41}, {76, 20}, {76, 20}}
+	if !reflect.DeepEqual(sizes, expected) {
+		t.Fatalf("expected %v, got %v", expected, sizes)
+	}
+
+	root.leaves()[0].sessionId = "other"
+	if err := applyLayoutSizes(tree, root); err == nil {
+		t.Fatal("expected an error for a tree that doesn't match the layout")
+	}
+}
@@ -0,0 +1,132 @@
+package itermctl_test
+
+import (
+	"encoding/json"
+	"gopkg.in/yaml.v3"
+	"mrz.io/itermctl"
+	"reflect"
+	"testing"
+)
+
+const testLayoutYaml = `
+directory: ~/src/project
+ready_timeout: 10s
+windows:
+  - name: dev
+    tabs:
+      - name: code
+        root:
+          split: vertical
+          panes:
+            - name: editor
+              size: 2
+              commands: [vim]
+            - split: horizontal
+              panes:
+                - name: tests
+                  directory: cmd
+                  commands: [go test ./...]
+                - name: shell
+                  profile: Dev Box
+                  variables:
+                    user.role: shell
+      - name: logs
+        root:
+          name: logs
+          properties:
+            Badge Text: logs
+          commands: [tail -f /var/log/system.log]
+`
+
+const testLayoutJson = `{
+  "directory": "~/src/project",
+  "ready_timeout": "10s",
+  "windows": [{
+    "name": "dev",
+    "tabs": [
+      {"name": "code", "root": {"split": "vertical", "panes": [
+        {"name": "editor", "size": 2, "commands": ["vim"]},
+        {"split": "horizontal", "panes": [
+          {"name": "tests", "directory": "cmd", "commands": ["go test ./..."]},
+          {"name": "shell", "profile": "Dev Box", "variables": {"user.role": "shell"}}
+        ]}
+      ]}},
+      {"name": "logs", "root": {"name": "logs", "properties": {"Badge Text": "logs"},
+        "commands": ["tail -f /var/log/system.log"]}}
+    ]
+  }]
+}`
+
+func TestParseLayout(t *testing.T) {
+	fromYaml, err := itermctl.ParseLayout([]byte(testLayoutYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fromJson, err := itermctl.ParseLayout([]byte(testLayoutJson))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fromYaml, fromJson) {
+		t.Fatalf("expected YAML and JSON layouts to be equal, got %v and %v", fromYaml, fromJson)
+	}
+
+	root := fromYaml.Windows[0].Tabs[0].Root
+	if root.Split != itermctl.SplitVertical || len(root.Panes) != 2 || root.Panes[0].Size != 2 {
+		t.Fatalf("unexpected root pane: %+v", root)
+	}
+
+	if shell := root.Panes[1].Panes[1]; shell.Variables["user.role"] != "shell" {
+		t.Fatalf("unexpected variables: %v", shell.Variables)
+	}
+}
+
+func TestParseLayout_Errors(t *testing.T) {
+	examples := map[string]string{
+		"no windows":        `windows: []`,
+		"no tabs":           `windows: [{name: w}]`,
+		"no root":           `windows: [{tabs: [{name: t}]}]`,
+		"bad timeout":       `{ready_timeout: soon, windows: [{tabs: [{root: {}}]}]}`,
+		"bad split":         `windows: [{tabs: [{root: {split: diagonal, panes: [{}, {}]}}]}]`,
+		"split no panes":    `windows: [{tabs: [{root: {split: vertical}}]}]`,
+		"panes no split":    `windows: [{tabs: [{root: {panes: [{}, {}]}}]}]`,
+		"negative size":     `windows: [{tabs: [{root: {split: vertical, panes: [{size: -1}, {}]}}]}]`,
+		"duplicate pane":    `windows: [{tabs: [{root: {split: vertical, panes: [{name: a}, {name: a}]}}]}]`,
+		"split commands":    `windows: [{tabs: [{root: {split: vertical, commands: [ls], panes: [{}, {}]}}]}]`,
+		"duplicate windows": `windows: [{name: w, tabs: [{root: {}}]}, {name: w, tabs: [{root: {}}]}]`,
+	}
+
+	for name, layout := range examples {
+		t.Run(name, func(t *testing.T) {
+			if _, err := itermctl.ParseLayout([]byte(layout)); err == nil {
+				t.Fatalf("expected an error for %s", layout)
+			}
+		})
+	}
+}
+
+func TestLayoutSpec_Marshal(t *testing.T) {
+	spec, err := itermctl.ParseLayout([]byte(testLayoutYaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, marshal := range map[string]func(interface{}) ([]byte, error){"yaml": yaml.Marshal, "json": json.Marshal} {
+		t.Run(name, func(t *testing.T) {
+			data, err := marshal(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := itermctl.ParseLayout(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(parsed, spec) {
+				t.Fatalf("expected %v, got %v", spec, parsed)
+			}
+		})
+	}
+}
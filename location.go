@@ -0,0 +1,250 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mrz.io/itermctl/iterm2"
+	"sync"
+)
+
+// Location is where a session's shell is, as reported by shell integration.
+type Location struct {
+	SessionId string
+	HostName  string
+	UserName  string
+	Directory string
+}
+
+// locationVariables are the session variables holding a Location's fields.
+var locationVariables = []string{"hostname", "username", "path"}
+
+// MonitorLocationChanges writes the location of the session to the returned channel each time it changes, until the
+// context is done or the Connection is closed. LocationChangeNotifications are deprecated, so for a single session
+// the location's variables ("hostname", "username" and "path") are monitored as well, and this works even if iTerm2
+// refuses the subscription to the notification. With AllSessions, only the notification is used; see
+// App.TrackLocations for a variable-based alternative.
+func MonitorLocationChanges(ctx context.Context, conn *Connection, sessionId string) (<-chan Location, error) {
+	req := NewNotificationRequest(true, iterm2.NotificationType_NOTIFY_ON_LOCATION_CHANGE, sessionId)
+
+	if sessionId == AllSessions || sessionId == "" {
+		recv, err := conn.Subscribe(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("location monitor: %w", err)
+		}
+
+		return forwardLocationChanges(ctx, recv.Ch()), nil
+	}
+
+	// cancelled on error, so that the subscriptions made so far don't hold up the Connection
+	ctx, cancel := context.WithCancel(ctx)
+
+	recv, subscribeErr := conn.Subscribe(ctx, req)
+
+	var notifications <-chan *iterm2.ServerOriginatedMessage
+	if subscribeErr == nil {
+		notifications = recv.Ch()
+	} else {
+		logrus.Debugf("location monitor: %s, monitoring variables only", subscribeErr)
+	}
+
+	current := Location{SessionId: sessionId}
+	variableChanges := make(chan VariableChange)
+	wg := &sync.WaitGroup{}
+
+	for _, name := range locationVariables {
+		initialValue, changes, err := monitorVariable(ctx, conn, iterm2.VariableScope_SESSION, sessionId, name)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("location monitor: %w", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			for change := range changes {
+				select {
+				case variableChanges <- change:
+				case <-ctx.Done():
+				}
+			}
+			wg.Done()
+		}()
+
+		value, err := variableString(initialValue)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("location monitor: %s: %w", name, err)
+		}
+		current.setVariable(name, value)
+	}
+
+	go func() {
+		wg.Wait()
+		close(variableChanges)
+	}()
+
+	locations := make(chan Location)
+
+	go func() {
+		defer close(locations)
+		defer cancel()
+
+		var changes <-chan VariableChange = variableChanges
+
+		for notifications != nil || changes != nil {
+			next := current
+
+			select {
+			case msg, ok := <-notifications:
+				if !ok {
+					notifications = nil
+					continue
+				}
+
+				n := msg.GetNotification().GetLocationChangeNotification()
+				if n == nil || n.GetSession() != sessionId {
+					continue
+				}
+				next.setNotification(n)
+
+			case change, ok := <-changes:
+				if !ok {
+					changes = nil
+					continue
+				}
+
+				_, value, err := change.Strings()
+				if err != nil {
+					logrus.Errorf("location monitor: %s", err)
+					continue
+				}
+				next.setVariable(change.Name, value)
+			}
+
+			if next != current {
+				current = next
+
+				select {
+				case locations <- current:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return locations, nil
+}
+
+// forwardLocationChanges converts LocationChangeNotifications of any session to Locations, skipping repeated ones, until
+// src is closed or the context is done.
+func forwardLocationChanges(ctx context.Context, src <-chan *iterm2.ServerOriginatedMessage) <-chan Location {
+	locations := make(chan Location)
+
+	go func() {
+		defer close(locations)
+
+		current := make(map[string]Location)
+
+		for msg := range src {
+			n := msg.GetNotification().GetLocationChangeNotification()
+			if n == nil {
+				continue
+			}
+
+			location := current[n.GetSession()]
+			location.SessionId = n.GetSession()
+			location.setNotification(n)
+
+			if location != current[n.GetSession()] {
+				current[n.GetSession()] = location
+
+				select {
+				case locations <- location:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return locations
+}
+
+func (l *Location) setNotification(n *iterm2.LocationChangeNotification) {
+	if n.HostName != nil {
+		l.HostName = n.GetHostName()
+	}
+
+	if n.UserName != nil {
+		l.UserName = n.GetUserName()
+	}
+
+	if n.Directory != nil {
+		l.Directory = n.GetDirectory()
+	}
+}
+
+func (l *Location) setVariable(name, value string) {
+	switch name {
+	case "hostname":
+		l.HostName = value
+	case "username":
+		l.UserName = value
+	case "path":
+		l.Directory = value
+	}
+}
+
+// TrackLocations starts keeping the location of all sessions up to date, until the context is done or the Connection is
+// closed. Use App.Location to read it. Calling it again stops the previous tracking.
+func (a *App) TrackLocations(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	watcher, err := WatchVariables(ctx, a, locationVariables...)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("track locations: %w", err)
+	}
+
+	go func() {
+		for range watcher.Changes() {
+		}
+	}()
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+
+	if a.stopLocations != nil {
+		a.stopLocations()
+	}
+
+	a.locations = watcher
+	a.stopLocations = cancel
+
+	return nil
+}
+
+// Location returns the last known location of a session. It returns false if App.TrackLocations wasn't called, or the
+// session isn't known.
+func (a *App) Location(sessionId string) (Location, bool) {
+	a.mx.Lock()
+	watcher := a.locations
+	a.mx.Unlock()
+
+	if watcher == nil {
+		return Location{}, false
+	}
+
+	location := Location{SessionId: sessionId}
+	known := false
+
+	for _, name := range locationVariables {
+		if value, ok := watcher.Value(sessionId, name); ok {
+			location.setVariable(name, value)
+			known = true
+		}
+	}
+
+	return location, known
+}
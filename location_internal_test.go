@@ -0,0 +1,96 @@
+package itermctl
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func locationChange(n *iterm2.LocationChangeNotification) *iterm2.ServerOriginatedMessage {
+	return &iterm2.ServerOriginatedMessage{
+		Submessage: &iterm2.ServerOriginatedMessage_Notification{
+			Notification: &iterm2.Notification{LocationChangeNotification: n},
+		},
+	}
+}
+
+func TestForwardLocationChanges(t *testing.T) {
+	src := make(chan *iterm2.ServerOriginatedMessage, 10)
+
+	src <- locationChange(&iterm2.LocationChangeNotification{
+		Session: proto.String("s1"), HostName: proto.String("host"), UserName: proto.String("user"),
+		Directory: proto.String("/tmp"),
+	})
+	src <- &iterm2.ServerOriginatedMessage{}
+	src <- locationChange(&iterm2.LocationChangeNotification{Session: proto.String("s2"), Directory: proto.String("/")})
+	src <- locationChange(&iterm2.LocationChangeNotification{Session: proto.String("s1"), Directory: proto.String("/tmp")})
+	src <- locationChange(&iterm2.LocationChangeNotification{Session: proto.String("s1"), Directory: proto.String("/home")})
+	close(src)
+
+	var locations []Location
+	for location := range forwardLocationChanges(context.Background(), src) {
+		locations = append(locations, location)
+	}
+
+	expected := []Location{
+		{SessionId: "s1", HostName: "host", UserName: "user", Directory: "/tmp"},
+		{SessionId: "s2", Directory: "/"},
+		{SessionId: "s1", HostName: "host", UserName: "user", Directory: "/home"},
+	}
+
+	if !reflect.DeepEqual(locations, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, locations)
+	}
+}
+
+func TestForwardLocationChanges_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	src := make(chan *iterm2.ServerOriginatedMessage, 1)
+	src <- locationChange(&iterm2.LocationChangeNotification{Session: proto.String("s1"), Directory: proto.String("/")})
+	defer close(src)
+
+	locations := forwardLocationChanges(ctx, src)
+
+	// nobody reads the location, the forwarder must stop anyway
+	cancel()
+	time.Sleep(100 * time.Millisecond)
+
+	select {
+	case location, ok := <-locations:
+		if ok {
+			t.Fatalf("expected the locations to be closed, got %+v", location)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the locations to be closed")
+	}
+}
+
+func TestLocation_SetNotification(t *testing.T) {
+	l := Location{SessionId: "s1", HostName: "host", UserName: "user", Directory: "/tmp"}
+
+	l.setNotification(&iterm2.LocationChangeNotification{Directory: proto.String("/home")})
+	l.setNotification(&iterm2.LocationChangeNotification{UserName: proto.String("")})
+
+	expected := Location{SessionId: "s1", HostName: "host", Directory: "/home"}
+	if l != expected {
+		t.Fatalf("expected %+v, got %+v", expected, l)
+	}
+}
+
+func TestLocation_SetVariable(t *testing.T) {
+	var l Location
+
+	l.setVariable("hostname", "host")
+	l.setVariable("username", "user")
+	l.setVariable("path", "/tmp")
+	l.setVariable("jobName", "vim")
+
+	expected := Location{HostName: "host", UserName: "user", Directory: "/tmp"}
+	if l != expected {
+		t.Fatalf("expected %+v, got %+v", expected, l)
+	}
+}
@@ -0,0 +1,67 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+)
+
+var (
+	ErrMenuItemBadIdentifier = fmt.Errorf("MenuItemResponse_BAD_IDENTIFIER")
+	ErrMenuItemDisabled      = fmt.Errorf("MenuItemResponse_DISABLED")
+)
+
+// MenuItem is the identifier of an item of iTerm2's main menu. See menuitems.go for the known identifiers.
+type MenuItem string
+
+// MenuItemState is the state of a menu item.
+type MenuItemState struct {
+	Checked bool
+	Enabled bool
+}
+
+// InvokeMenuItem selects a menu item, as if clicked. ErrMenuItemDisabled is returned if the item is disabled.
+// See https://iterm2.com/python-api/mainmenu.html.
+func (a *App) InvokeMenuItem(id MenuItem) error {
+	if _, err := a.sendMenuItemRequest(id, false); err != nil {
+		return fmt.Errorf("invoke menu item %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// MenuItemState tells if a menu item is checked and enabled, without invoking it.
+func (a *App) MenuItemState(id MenuItem) (MenuItemState, error) {
+	resp, err := a.sendMenuItemRequest(id, true)
+	if err != nil {
+		return MenuItemState{}, fmt.Errorf("menu item state %q: %w", id, err)
+	}
+
+	return MenuItemState{Checked: resp.GetChecked(), Enabled: resp.GetEnabled()}, nil
+}
+
+func (a *App) sendMenuItemRequest(id MenuItem, queryOnly bool) (*iterm2.MenuItemResponse, error) {
+	identifier := string(id)
+
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_MenuItemRequest{
+			MenuItemRequest: &iterm2.MenuItemRequest{Identifier: &identifier, QueryOnly: &queryOnly},
+		},
+	}
+
+	resp, err := a.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.GetMenuItemResponse().GetStatus() {
+	case iterm2.MenuItemResponse_OK:
+		return resp.GetMenuItemResponse(), nil
+	case iterm2.MenuItemResponse_BAD_IDENTIFIER:
+		return nil, ErrMenuItemBadIdentifier
+	case iterm2.MenuItemResponse_DISABLED:
+		return nil, ErrMenuItemDisabled
+	default:
+		return nil, fmt.Errorf("%s", resp.GetMenuItemResponse().GetStatus())
+	}
+}
@@ -0,0 +1,219 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/internal/json"
+	"mrz.io/itermctl/iterm2"
+	"sort"
+)
+
+var (
+	ErrPreferenceUnrecognizedRequest = fmt.Errorf("PreferencesResponse_UNRECOGNIZED_REQUEST")
+	ErrPreferenceBadJson             = fmt.Errorf("SetPreferenceResult_BAD_JSON")
+	ErrPreferenceInvalidValue        = fmt.Errorf("SetPreferenceResult_INVALID_VALUE")
+	ErrBadGuid                       = fmt.Errorf("SetDefaultProfileResult_BAD_GUID")
+)
+
+// PreferenceResult is the outcome of getting or setting a single preference in a batch. Err is set when iTerm2 refused
+// that item; the other items of the batch are unaffected.
+type PreferenceResult struct {
+	Key       string
+	JsonValue string
+	Err       error
+}
+
+// Value unmarshals the preference's value into target. Preferences without a value nor a default unmarshal as null.
+func (r PreferenceResult) Value(target interface{}) error {
+	if r.Err != nil {
+		return fmt.Errorf("preference %q: %w", r.Key, r.Err)
+	}
+
+	if err := json.UnmarshalString(r.JsonValue, target); err != nil {
+		return fmt.Errorf("preference %q: %w", r.Key, err)
+	}
+
+	return nil
+}
+
+// Preference unmarshals the value of a global preference, such as "TabStyleWithAutomaticOption", into target.
+// See https://iterm2.com/python-api/preferences.html.
+func (a *App) Preference(key string, target interface{}) error {
+	results, err := a.Preferences(key)
+	if err != nil {
+		return err
+	}
+
+	return results[0].Value(target)
+}
+
+// Preferences reads many global preferences with a single request, returning one result per key in the same order.
+func (a *App) Preferences(keys ...string) ([]PreferenceResult, error) {
+	var requests []*iterm2.PreferencesRequest_Request
+
+	for _, key := range keys {
+		key := key
+		requests = append(requests, &iterm2.PreferencesRequest_Request{
+			Request: &iterm2.PreferencesRequest_Request_GetPreferenceRequest{
+				GetPreferenceRequest: &iterm2.PreferencesRequest_Request_GetPreference{Key: &key},
+			},
+		})
+	}
+
+	resp, err := sendPreferencesRequest(a.conn, requests)
+	if err != nil {
+		return nil, fmt.Errorf("get preferences: %w", err)
+	}
+
+	results := make([]PreferenceResult, len(keys))
+
+	for i, r := range resp.GetResults() {
+		results[i].Key = keys[i]
+
+		if r.GetUnrecognizedRequest() != nil {
+			results[i].Err = ErrPreferenceUnrecognizedRequest
+			continue
+		}
+
+		results[i].JsonValue = "null"
+		if r.GetGetPreferenceResult().JsonValue != nil {
+			results[i].JsonValue = r.GetGetPreferenceResult().GetJsonValue()
+		}
+	}
+
+	return results, nil
+}
+
+// SetPreference sets a global preference.
+func (a *App) SetPreference(key string, value interface{}) error {
+	results, err := a.SetPreferences(map[string]interface{}{key: value})
+	if err != nil {
+		return err
+	}
+
+	if results[0].Err != nil {
+		return fmt.Errorf("set preference %q: %w", key, results[0].Err)
+	}
+
+	return nil
+}
+
+// SetPreferences sets many global preferences with a single request, returning one result per key, sorted by key.
+func (a *App) SetPreferences(values map[string]interface{}) ([]PreferenceResult, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var requests []*iterm2.PreferencesRequest_Request
+	results := make([]PreferenceResult, len(keys))
+
+	for i, key := range keys {
+		key := key
+		value, err := json.MarshalString(values[key])
+		if err != nil {
+			return nil, fmt.Errorf("set preferences: %q: %w", key, err)
+		}
+
+		results[i] = PreferenceResult{Key: key, JsonValue: value}
+
+		requests = append(requests, &iterm2.PreferencesRequest_Request{
+			Request: &iterm2.PreferencesRequest_Request_SetPreferenceRequest{
+				SetPreferenceRequest: &iterm2.PreferencesRequest_Request_SetPreference{Key: &key, JsonValue: &value},
+			},
+		})
+	}
+
+	resp, err := sendPreferencesRequest(a.conn, requests)
+	if err != nil {
+		return nil, fmt.Errorf("set preferences: %w", err)
+	}
+
+	for i, r := range resp.GetResults() {
+		if r.GetUnrecognizedRequest() != nil {
+			results[i].Err = ErrPreferenceUnrecognizedRequest
+			continue
+		}
+
+		switch r.GetSetPreferenceResult().GetStatus() {
+		case iterm2.PreferencesResponse_Result_SetPreferenceResult_OK:
+		case iterm2.PreferencesResponse_Result_SetPreferenceResult_BAD_JSON:
+			results[i].Err = ErrPreferenceBadJson
+		case iterm2.PreferencesResponse_Result_SetPreferenceResult_INVALID_VALUE:
+			results[i].Err = ErrPreferenceInvalidValue
+		default:
+			results[i].Err = fmt.Errorf("%s", r.GetSetPreferenceResult().GetStatus())
+		}
+	}
+
+	return results, nil
+}
+
+// DefaultProfile returns the GUID of the default profile.
+func (a *App) DefaultProfile() (string, error) {
+	resp, err := sendPreferencesRequest(a.conn, []*iterm2.PreferencesRequest_Request{{
+		Request: &iterm2.PreferencesRequest_Request_GetDefaultProfileRequest{
+			GetDefaultProfileRequest: &iterm2.PreferencesRequest_Request_GetDefaultProfile{},
+		},
+	}})
+
+	if err != nil {
+		return "", fmt.Errorf("get default profile: %w", err)
+	}
+
+	result := resp.GetResults()[0]
+	if result.GetUnrecognizedRequest() != nil {
+		return "", fmt.Errorf("get default profile: %w", ErrPreferenceUnrecognizedRequest)
+	}
+
+	return result.GetGetDefaultProfileResult().GetGuid(), nil
+}
+
+// SetDefaultProfile makes the profile with the given GUID the default profile. ErrBadGuid is returned if there's no
+// such profile.
+func (a *App) SetDefaultProfile(guid string) error {
+	resp, err := sendPreferencesRequest(a.conn, []*iterm2.PreferencesRequest_Request{{
+		Request: &iterm2.PreferencesRequest_Request_SetDefaultProfileRequest{
+			SetDefaultProfileRequest: &iterm2.PreferencesRequest_Request_SetDefaultProfile{Guid: &guid},
+		},
+	}})
+
+	if err != nil {
+		return fmt.Errorf("set default profile: %w", err)
+	}
+
+	result := resp.GetResults()[0]
+	if result.GetUnrecognizedRequest() != nil {
+		return fmt.Errorf("set default profile: %w", ErrPreferenceUnrecognizedRequest)
+	}
+
+	switch result.GetSetDefaultProfileResult().GetStatus() {
+	case iterm2.PreferencesResponse_Result_SetDefaultProfileResult_OK:
+		return nil
+	case iterm2.PreferencesResponse_Result_SetDefaultProfileResult_BAD_GUID:
+		return fmt.Errorf("set default profile: %w", ErrBadGuid)
+	default:
+		return fmt.Errorf("set default profile: %s", result.GetSetDefaultProfileResult().GetStatus())
+	}
+}
+
+func sendPreferencesRequest(conn *Connection, requests []*iterm2.PreferencesRequest_Request) (*iterm2.PreferencesResponse, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_PreferencesRequest{
+			PreferencesRequest: &iterm2.PreferencesRequest{Requests: requests},
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.GetPreferencesResponse().GetResults()) != len(requests) {
+		return nil, fmt.Errorf("expected %d results, got %d", len(requests),
+			len(resp.GetPreferencesResponse().GetResults()))
+	}
+
+	return resp.GetPreferencesResponse(), nil
+}
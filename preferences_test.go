@@ -0,0 +1,23 @@
+package itermctl_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"testing"
+)
+
+func TestPreferenceResult_Value(t *testing.T) {
+	var value int
+	if err := (itermctl.PreferenceResult{Key: "foo", JsonValue: "42"}).Value(&value); err != nil {
+		t.Fatal(err)
+	}
+
+	if value != 42 {
+		t.Fatalf("expected 42, got %d", value)
+	}
+
+	result := itermctl.PreferenceResult{Key: "foo", Err: itermctl.ErrPreferenceUnrecognizedRequest}
+	if err := result.Value(&value); !errors.Is(err, itermctl.ErrPreferenceUnrecognizedRequest) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrPreferenceUnrecognizedRequest, err)
+	}
+}
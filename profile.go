@@ -0,0 +1,385 @@
+package itermctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+	"sort"
+)
+
+const (
+	ColorSpaceSRGB       = "sRGB"
+	ColorSpaceCalibrated = "Calibrated"
+)
+
+// Names of frequently used profile properties. See SetProfilePropertyRequest in the API's proto file for the complete
+// list, and AnsiColorKey for the names of the ANSI colors.
+const (
+	ProfileName              = "Name"
+	ProfileGuid              = "Guid"
+	ProfileTags              = "Tags"
+	ProfileBadgeText         = "Badge Text"
+	ProfileNormalFont        = "Normal Font"
+	ProfileNonAsciiFont      = "Non Ascii Font"
+	ProfileCustomCommand     = "Custom Command"
+	ProfileCommand           = "Command"
+	ProfileCustomDirectory   = "Custom Directory"
+	ProfileWorkingDirectory  = "Working Directory"
+	ProfileForegroundColor   = "Foreground Color"
+	ProfileBackgroundColor   = "Background Color"
+	ProfileBoldColor         = "Bold Color"
+	ProfileCursorColor       = "Cursor Color"
+	ProfileCursorTextColor   = "Cursor Text Color"
+	ProfileSelectionColor    = "Selection Color"
+	ProfileSelectedTextColor = "Selected Text Color"
+	ProfileLinkColor         = "Link Color"
+	ProfileBadgeColor        = "Badge Color"
+	ProfileTabColor          = "Tab Color"
+	ProfileUseTabColor       = "Use Tab Color"
+)
+
+// AnsiColorKey returns the name of the profile property holding the i-th ANSI color, from 0 to 15.
+func AnsiColorKey(i int) string {
+	return fmt.Sprintf("Ansi %d Color", i)
+}
+
+// Color is a color as stored in profiles, with components in the [0, 1] range.
+type Color struct {
+	Red        float64
+	Green      float64
+	Blue       float64
+	Alpha      float64
+	ColorSpace string
+}
+
+type colorJson struct {
+	Red        float64 `json:"Red Component"`
+	Green      float64 `json:"Green Component"`
+	Blue       float64 `json:"Blue Component"`
+	Alpha      float64 `json:"Alpha Component"`
+	ColorSpace string  `json:"Color Space,omitempty"`
+}
+
+// NewColor creates an opaque sRGB Color from 8-bit components.
+func NewColor(red, green, blue uint8) Color {
+	return Color{
+		Red:        float64(red) / 255,
+		Green:      float64(green) / 255,
+		Blue:       float64(blue) / 255,
+		Alpha:      1,
+		ColorSpace: ColorSpaceSRGB,
+	}
+}
+
+// MarshalJSON encodes the Color as iTerm2 does in profiles.
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(colorJson(c))
+}
+
+// UnmarshalJSON decodes a Color encoded as iTerm2 does in profiles. A missing alpha component means the color is
+// opaque.
+func (c *Color) UnmarshalJSON(data []byte) error {
+	v := colorJson{Alpha: 1}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*c = Color(v)
+	return nil
+}
+
+// Assignment is a value to assign to a profile property. The value is marshaled to JSON, so any Go value that
+// marshals as the property expects can be used, including Color.
+type Assignment struct {
+	Key   string
+	Value interface{}
+}
+
+// Profile is a set of properties describing a session's appearance and behavior. Its most frequently used properties
+// are available as fields, while Properties holds all of them, JSON encoded.
+// See https://iterm2.com/python-api/profile.html.
+type Profile struct {
+	Name             string   `json:"Name"`
+	Guid             string   `json:"Guid"`
+	Tags             []string `json:"Tags"`
+	BadgeText        string   `json:"Badge Text"`
+	NormalFont       string   `json:"Normal Font"`
+	NonAsciiFont     string   `json:"Non Ascii Font"`
+	CustomCommand    string   `json:"Custom Command"`
+	Command          string   `json:"Command"`
+	CustomDirectory  string   `json:"Custom Directory"`
+	WorkingDirectory string   `json:"Working Directory"`
+
+	ForegroundColor   *Color `json:"Foreground Color"`
+	BackgroundColor   *Color `json:"Background Color"`
+	BoldColor         *Color `json:"Bold Color"`
+	CursorColor       *Color `json:"Cursor Color"`
+	CursorTextColor   *Color `json:"Cursor Text Color"`
+	SelectionColor    *Color `json:"Selection Color"`
+	SelectedTextColor *Color `json:"Selected Text Color"`
+	LinkColor         *Color `json:"Link Color"`
+	BadgeColor        *Color `json:"Badge Color"`
+	TabColor          *Color `json:"Tab Color"`
+
+	// AnsiColors are the 16 ANSI colors, nil when not set.
+	AnsiColors [16]*Color `json:"-"`
+
+	// Properties holds all the profile's properties, JSON encoded.
+	Properties map[string]string `json:"-"`
+
+	conn      *Connection
+	sessionId string
+}
+
+func newProfile(conn *Connection, sessionId string, properties []*iterm2.ProfileProperty) (*Profile, error) {
+	p := &Profile{conn: conn, sessionId: sessionId, Properties: make(map[string]string)}
+
+	for _, prop := range properties {
+		p.Properties[prop.GetKey()] = prop.GetJsonValue()
+	}
+
+	if err := p.decode(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *Profile) decode() error {
+	raw := make(map[string]json.RawMessage)
+	for key, value := range p.Properties {
+		raw[key] = json.RawMessage(value)
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+
+	decoded := Profile{conn: p.conn, sessionId: p.sessionId, Properties: p.Properties}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("profile %q: %w", p.Properties[ProfileName], err)
+	}
+
+	for i := range decoded.AnsiColors {
+		if value, ok := raw[AnsiColorKey(i)]; ok {
+			decoded.AnsiColors[i] = &Color{}
+			if err := json.Unmarshal(value, decoded.AnsiColors[i]); err != nil {
+				return fmt.Errorf("profile %q: %w", p.Properties[ProfileName], err)
+			}
+		}
+	}
+
+	*p = decoded
+	return nil
+}
+
+// SessionId returns the ID of the session owning this profile, or an empty string if this is not a session's copy of
+// a profile.
+func (p *Profile) SessionId() string {
+	return p.sessionId
+}
+
+// Property unmarshals the value of a property into target. Properties that are not set unmarshal as JSON null.
+func (p *Profile) Property(key string, target interface{}) error {
+	value, ok := p.Properties[key]
+	if !ok {
+		value = "null"
+	}
+
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		return fmt.Errorf("profile property %q: %w", key, err)
+	}
+
+	return nil
+}
+
+// SetProperties assigns the given values with a single request. If the profile is a session's copy only that copy is
+// modified, otherwise the change applies to the underlying profile and all the sessions using it. On success the
+// Profile is updated with the new values.
+func (p *Profile) SetProperties(assignments ...Assignment) error {
+	req := &iterm2.SetProfilePropertyRequest{}
+
+	if p.sessionId != "" {
+		req.Target = &iterm2.SetProfilePropertyRequest_Session{Session: p.sessionId}
+	} else {
+		req.Target = &iterm2.SetProfilePropertyRequest_GuidList_{
+			GuidList: &iterm2.SetProfilePropertyRequest_GuidList{Guids: []string{p.Guid}},
+		}
+	}
+
+	if err := setProfileProperties(p.conn, req, assignments); err != nil {
+		return err
+	}
+
+	for _, a := range assignments {
+		value, err := json.Marshal(a.Value)
+		if err != nil {
+			return fmt.Errorf("set profile properties: %w", err)
+		}
+		p.Properties[a.Key] = string(value)
+	}
+
+	return p.decode()
+}
+
+// Profiles lists the profiles with the given GUIDs, or all profiles if no GUID is given.
+func (a *App) Profiles(guids ...string) ([]*Profile, error) {
+	return listProfiles(a.conn, guids...)
+}
+
+func listProfiles(conn *Connection, guids ...string) ([]*Profile, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_ListProfilesRequest{
+			ListProfilesRequest: &iterm2.ListProfilesRequest{Guids: guids},
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("list profiles: %w", err)
+	}
+
+	var profiles []*Profile
+
+	for _, p := range resp.GetListProfilesResponse().GetProfiles() {
+		profile, err := newProfile(conn, "", p.GetProperties())
+		if err != nil {
+			return nil, fmt.Errorf("list profiles: %w", err)
+		}
+		profiles = append(profiles, profile)
+	}
+
+	sort.SliceStable(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, nil
+}
+
+// Profile returns the profile with the given GUID, or nil if there's no such profile.
+func (a *App) Profile(guid string) (*Profile, error) {
+	profiles, err := a.Profiles(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range profiles {
+		if p.Guid == guid {
+			return p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ProfileByName returns the first profile with the given name, or nil if there's no such profile.
+func (a *App) ProfileByName(name string) (*Profile, error) {
+	profiles, err := a.Profiles()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SetProfileProperties assigns the given values to the profiles with the given GUIDs, with a single request. Sessions
+// using those profiles are updated too.
+func (a *App) SetProfileProperties(guids []string, assignments ...Assignment) error {
+	return setProfileProperties(a.conn, &iterm2.SetProfilePropertyRequest{
+		Target: &iterm2.SetProfilePropertyRequest_GuidList_{
+			GuidList: &iterm2.SetProfilePropertyRequest_GuidList{Guids: guids},
+		},
+	}, assignments)
+}
+
+// Profile returns the session's copy of its profile, which may differ from the underlying profile.
+func (s *Session) Profile() (*Profile, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_GetProfilePropertyRequest{
+			GetProfilePropertyRequest: &iterm2.GetProfilePropertyRequest{Session: &s.id},
+		},
+	}
+
+	resp, err := s.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("session profile: %w", err)
+	}
+
+	if resp.GetGetProfilePropertyResponse().GetStatus() != iterm2.GetProfilePropertyResponse_OK {
+		return nil, fmt.Errorf("session profile: %s", resp.GetGetProfilePropertyResponse().GetStatus())
+	}
+
+	profile, err := newProfile(s.conn, s.id, resp.GetGetProfilePropertyResponse().GetProperties())
+	if err != nil {
+		return nil, fmt.Errorf("session profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// SetProfileProperties assigns the given values to the session's copy of its profile, with a single request, leaving
+// the underlying profile untouched.
+func (s *Session) SetProfileProperties(assignments ...Assignment) error {
+	return setProfileProperties(s.conn, &iterm2.SetProfilePropertyRequest{
+		Target: &iterm2.SetProfilePropertyRequest_Session{Session: s.id},
+	}, assignments)
+}
+
+// profileProperties turns assignments into the ProfileProperty form used to customize new tabs and split panes.
+func profileProperties(assignments []Assignment) ([]*iterm2.ProfileProperty, error) {
+	var properties []*iterm2.ProfileProperty
+
+	for _, a := range assignments {
+		key := a.Key
+		value, err := json.Marshal(a.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", key, err)
+		}
+
+		jsonValue := string(value)
+		properties = append(properties, &iterm2.ProfileProperty{Key: &key, JsonValue: &jsonValue})
+	}
+
+	return properties, nil
+}
+
+func setProfileProperties(conn *Connection, setReq *iterm2.SetProfilePropertyRequest, assignments []Assignment) error {
+	for _, a := range assignments {
+		key := a.Key
+		value, err := json.Marshal(a.Value)
+		if err != nil {
+			return fmt.Errorf("set profile properties: %q: %w", key, err)
+		}
+
+		jsonValue := string(value)
+		setReq.Assignments = append(setReq.Assignments, &iterm2.SetProfilePropertyRequest_Assignment{
+			Key:       &key,
+			JsonValue: &jsonValue,
+		})
+	}
+
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_SetProfilePropertyRequest{
+			SetProfilePropertyRequest: setReq,
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("set profile properties: %w", err)
+	}
+
+	if resp.GetSetProfilePropertyResponse().GetStatus() != iterm2.SetProfilePropertyResponse_OK {
+		return fmt.Errorf("set profile properties: %s", resp.GetSetProfilePropertyResponse().GetStatus())
+	}
+
+	return nil
+}
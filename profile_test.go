@@ -0,0 +1,41 @@
+package itermctl_test
+
+import (
+	"encoding/json"
+	"mrz.io/itermctl"
+	"testing"
+)
+
+func TestColor_MarshalJSON(t *testing.T) {
+	color := itermctl.NewColor(255, 0, 51)
+
+	data, err := json.Marshal(color)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"Red Component":1,"Green Component":0,"Blue Component":0.2,"Alpha Component":1,"Color Space":"sRGB"}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+
+	var decoded itermctl.Color
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != color {
+		t.Fatalf("expected %v, got %v", color, decoded)
+	}
+}
+
+func TestColor_UnmarshalJSON_DefaultAlpha(t *testing.T) {
+	var color itermctl.Color
+	if err := json.Unmarshal([]byte(`{"Red Component":0.5,"Green Component":0.5,"Blue Component":0.5}`), &color); err != nil {
+		t.Fatal(err)
+	}
+
+	if color.Alpha != 1 {
+		t.Fatalf("expected an opaque color, got alpha %f", color.Alpha)
+	}
+}
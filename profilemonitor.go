@@ -0,0 +1,68 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+)
+
+// ErrProfileNotFound is returned by ProfileChange.Profile when the changed profile was deleted.
+var ErrProfileNotFound = fmt.Errorf("profile not found")
+
+// ProfileChange tells that a profile was modified, for example in the Preferences window.
+type ProfileChange struct {
+	Guid string
+	conn *Connection
+}
+
+// Profile fetches the changed profile as it is now, or returns ErrProfileNotFound if it was deleted.
+func (c ProfileChange) Profile() (*Profile, error) {
+	profiles, err := listProfiles(c.conn, c.Guid)
+	if err != nil {
+		return nil, fmt.Errorf("changed profile: %w", err)
+	}
+
+	for _, p := range profiles {
+		if p.Guid == c.Guid {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("changed profile: %w: %s", ErrProfileNotFound, c.Guid)
+}
+
+// MonitorProfileChanges subscribes to ProfileChangedNotifications for the profile with the given GUID and writes a
+// ProfileChange to the returned channel each time the profile changes, until the context is done or the Connection is
+// closed. The GUID is required.
+func MonitorProfileChanges(ctx context.Context, conn *Connection, guid string) (<-chan ProfileChange, error) {
+	if guid == "" {
+		return nil, fmt.Errorf("profile change monitor: empty GUID")
+	}
+
+	req := NewNotificationRequest(true, iterm2.NotificationType_NOTIFY_ON_PROFILE_CHANGE, "")
+	req.Arguments = &iterm2.NotificationRequest_ProfileChangeRequest{
+		ProfileChangeRequest: &iterm2.ProfileChangeRequest{Guid: &guid},
+	}
+
+	recv, err := conn.Subscribe(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("profile change monitor: %w", err)
+	}
+
+	changes := make(chan ProfileChange)
+
+	go func() {
+		for msg := range recv.Ch() {
+			n := msg.GetNotification().GetProfileChangedNotification()
+			if n == nil || n.GetGuid() != guid {
+				continue
+			}
+
+			changes <- ProfileChange{Guid: n.GetGuid(), conn: conn}
+		}
+
+		close(changes)
+	}()
+
+	return changes, nil
+}
@@ -4,8 +4,45 @@ import (
 	"context"
 	"fmt"
 	"mrz.io/itermctl/iterm2"
+	"mrz.io/itermctl/screen"
+	"time"
 )
 
+var (
+	ErrPromptRequestMalformed = fmt.Errorf("GetPromptResponse_REQUEST_MALFORMED")
+	ErrPromptUnavailable      = fmt.Errorf("GetPromptResponse_PROMPT_UNAVAILABLE")
+	ErrPromptNotEditing       = fmt.Errorf("session is not waiting for a command")
+)
+
+// PromptState tells whether a Prompt's command is being edited, is running or has finished.
+type PromptState int32
+
+const (
+	PromptEditing  = PromptState(iterm2.GetPromptResponse_EDITING)
+	PromptRunning  = PromptState(iterm2.GetPromptResponse_RUNNING)
+	PromptFinished = PromptState(iterm2.GetPromptResponse_FINISHED)
+)
+
+// Prompt is a shell prompt of a session, with the command entered at the prompt and its output. Note that iTerm2 can
+// only detect prompts when shell integration is installed.
+// See https://iterm2.com/python-api/prompt.html#iterm2.Prompt.
+type Prompt struct {
+	Id        string
+	SessionId string
+
+	PromptRange  screen.CoordRange
+	CommandRange screen.CoordRange
+	OutputRange  screen.CoordRange
+
+	WorkingDirectory string
+	Command          string
+	State            PromptState
+	// ExitStatus is the command's exit status, once State is PromptFinished.
+	ExitStatus int32
+
+	session *Session
+}
+
 // MonitorPrompts subscribe to PromptNotification for the given modes, and writes them to the returned channel, until
 // the given context is done or the Connection is shutdown. Note that iTerm2 can only detect prompts when shell
 // integration is installed.
@@ -44,3 +81,209 @@ func MonitorPrompts(ctx context.Context, conn *Connection, sessionId string, mod
 
 	return prompts, nil
 }
+
+// Prompts lists the prompts of the session which are still in its history, oldest first.
+func (s *Session) Prompts(ctx context.Context) ([]*Prompt, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_ListPromptsRequest{
+			ListPromptsRequest: &iterm2.ListPromptsRequest{Session: &s.id},
+		},
+	}
+
+	resp, err := s.conn.GetResponse(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("list prompts: %w", err)
+	}
+
+	switch resp.GetListPromptsResponse().GetStatus() {
+	case iterm2.ListPromptsResponse_OK:
+	case iterm2.ListPromptsResponse_SESSION_NOT_FOUND:
+		return nil, fmt.Errorf("list prompts: %w", ErrSessionNotFound)
+	default:
+		return nil, fmt.Errorf("list prompts: %s", resp.GetListPromptsResponse().GetStatus())
+	}
+
+	var prompts []*Prompt
+
+	for _, id := range resp.GetListPromptsResponse().GetUniquePromptId() {
+		prompt, err := s.getPrompt(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("list prompts: %w", err)
+		}
+
+		prompts = append(prompts, prompt)
+	}
+
+	return prompts, nil
+}
+
+// Prompt returns the prompt with the given ID, such as PromptNotification's unique_prompt_id. ErrPromptUnavailable is
+// returned if the prompt is not in the session's history anymore.
+func (s *Session) Prompt(id string) (*Prompt, error) {
+	prompt, err := s.getPrompt(context.Background(), id)
+	if err != nil {
+		return nil, fmt.Errorf("prompt: %w", err)
+	}
+
+	return prompt, nil
+}
+
+// LastPrompt returns the most recent prompt of the session.
+func (s *Session) LastPrompt() (*Prompt, error) {
+	prompt, err := s.getPrompt(context.Background(), "")
+	if err != nil {
+		return nil, fmt.Errorf("last prompt: %w", err)
+	}
+
+	return prompt, nil
+}
+
+// Output returns the text printed by the prompt's command so far.
+func (p *Prompt) Output() (string, error) {
+	resp, err := p.session.ScreenContents(p.OutputRange.Windowed().Proto())
+	if err != nil {
+		return "", fmt.Errorf("prompt output: %w", err)
+	}
+
+	return ToString(resp.GetContents()), nil
+}
+
+func (s *Session) getPrompt(ctx context.Context, id string) (*Prompt, error) {
+	getPromptReq := &iterm2.GetPromptRequest{Session: &s.id}
+	if id != "" {
+		getPromptReq.UniquePromptId = &id
+	}
+
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_GetPromptRequest{GetPromptRequest: getPromptReq},
+	}
+
+	resp, err := s.conn.GetResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	promptResp := resp.GetGetPromptResponse()
+
+	switch promptResp.GetStatus() {
+	case iterm2.GetPromptResponse_OK:
+	case iterm2.GetPromptResponse_SESSION_NOT_FOUND:
+		return nil, ErrSessionNotFound
+	case iterm2.GetPromptResponse_REQUEST_MALFORMED:
+		return nil, ErrPromptRequestMalformed
+	case iterm2.GetPromptResponse_PROMPT_UNAVAILABLE:
+		return nil, ErrPromptUnavailable
+	default:
+		return nil, fmt.Errorf("%s", promptResp.GetStatus())
+	}
+
+	return &Prompt{
+		Id:               promptResp.GetUniquePromptId(),
+		SessionId:        s.id,
+		PromptRange:      screen.NewCoordRange(promptResp.GetPromptRange()),
+		CommandRange:     screen.NewCoordRange(promptResp.GetCommandRange()),
+		OutputRange:      screen.NewCoordRange(promptResp.GetOutputRange()),
+		WorkingDirectory: promptResp.GetWorkingDirectory(),
+		Command:          promptResp.GetCommand(),
+		State:            PromptState(promptResp.GetPromptState()),
+		ExitStatus:       int32(promptResp.GetExitStatus()),
+		session:          s,
+	}, nil
+}
+
+// CommandResult is the outcome of a command run with Session.Run.
+type CommandResult struct {
+	Prompt     *Prompt
+	ExitStatus int32
+	Duration   time.Duration
+	Output     string
+}
+
+// RunOptions configures Session.RunWithOptions.
+type RunOptions struct {
+	// InterruptOnCancel sends Ctrl-C to the session when the context is done before the command ends.
+	InterruptOnCancel bool
+}
+
+// Run sends a command to the session's shell and waits until it ends. The session must be at a prompt with no running
+// command, otherwise ErrPromptNotEditing is returned, and its shell must have shell integration installed. Any text
+// already typed at the prompt is discarded with Ctrl-E Ctrl-U before sending the command, so that they aren't run
+// together. If the context is done before the command ends, Run returns the context's error and leaves the command
+// running.
+func (s *Session) Run(ctx context.Context, command string) (*CommandResult, error) {
+	return s.RunWithOptions(ctx, command, RunOptions{})
+}
+
+// RunWithOptions is Run, configured by the given options.
+func (s *Session) RunWithOptions(ctx context.Context, command string, opts RunOptions) (*CommandResult, error) {
+	monitorCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	notifications, err := MonitorPrompts(monitorCtx, s.conn, s.id,
+		iterm2.PromptMonitorMode_COMMAND_START, iterm2.PromptMonitorMode_COMMAND_END)
+	if err != nil {
+		return nil, fmt.Errorf("run: %w", err)
+	}
+
+	defer func() {
+		cancel()
+		for range notifications {
+		}
+	}()
+
+	prompt, err := s.getPrompt(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("run: %w", err)
+	}
+
+	if prompt.State != PromptEditing {
+		return nil, fmt.Errorf("run: %w", ErrPromptNotEditing)
+	}
+
+	// move to the end of the line and kill it backwards, which clears the line in bash, zsh and fish
+	if err := s.SendText("\x05\x15"+command+"\n", false); err != nil {
+		return nil, fmt.Errorf("run: %w", err)
+	}
+
+	start := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if opts.InterruptOnCancel {
+				if err := s.SendText("\x03", false); err != nil {
+					return nil, fmt.Errorf("run: %w", err)
+				}
+			}
+			return nil, fmt.Errorf("run: %w", ctx.Err())
+		case n, ok := <-notifications:
+			if !ok {
+				return nil, fmt.Errorf("run: %w", ErrClosed)
+			}
+
+			if n.GetUniquePromptId() != prompt.Id {
+				continue
+			}
+
+			if n.GetCommandStart() != nil {
+				start = time.Now()
+			}
+
+			if n.GetCommandEnd() == nil {
+				continue
+			}
+
+			result := &CommandResult{ExitStatus: n.GetCommandEnd().GetStatus(), Duration: time.Since(start)}
+
+			if result.Prompt, err = s.getPrompt(ctx, prompt.Id); err != nil {
+				return nil, fmt.Errorf("run: %w", err)
+			}
+
+			if result.Output, err = result.Prompt.Output(); err != nil {
+				return nil, fmt.Errorf("run: %w", err)
+			}
+
+			return result, nil
+		}
+	}
+}
@@ -0,0 +1,610 @@
+package itermctl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"mrz.io/itermctl/iterm2"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var ErrVariableNotCached = fmt.Errorf("variable not cached")
+
+// SessionState describes a session as seen by a SessionQuery: where it is, what it is titled, whether it's buried or
+// focused and the values of its variables.
+type SessionState struct {
+	SessionId string
+	WindowId  string
+	TabId     string
+	Title     string
+	Buried    bool
+	Focused   bool
+
+	// Variables holds the values of the session's variables that are already known, as returned by Variable.
+	Variables map[string]string
+
+	lookup func(name string) (string, error)
+}
+
+// Variable returns the value of the named session variable (eg. "path", "jobName", "user.gitBranch"). String values
+// are returned as they are, unset variables as the empty string and any other value in its JSON form. Values are
+// looked up with iTerm2 the first time they are needed, unless the SessionState comes from a Snapshot, in which case
+// ErrVariableNotCached is returned for any variable that was not captured with the Snapshot.
+func (s *SessionState) Variable(name string) (string, error) {
+	if value, ok := s.Variables[name]; ok {
+		return value, nil
+	}
+
+	if s.lookup == nil {
+		return "", fmt.Errorf("%q: %w", name, ErrVariableNotCached)
+	}
+
+	value, err := s.lookup(name)
+	if err != nil {
+		return "", err
+	}
+
+	if s.Variables == nil {
+		s.Variables = make(map[string]string)
+	}
+
+	s.Variables[name] = value
+	return value, nil
+}
+
+// A SessionSource provides the SessionStates that a SessionQuery filters. App is a SessionSource whose states are
+// built from iTerm2's current state and look variables up on demand, while a Snapshot serves cached states.
+type SessionSource interface {
+	SessionStates() ([]*SessionState, error)
+}
+
+// Snapshot is a SessionSource serving states captured earlier, for example with App.Snapshot. Querying a Snapshot
+// never talks to iTerm2.
+type Snapshot []*SessionState
+
+// SessionStates returns the Snapshot's states.
+func (s Snapshot) SessionStates() ([]*SessionState, error) {
+	return s, nil
+}
+
+// SessionStates lists the sessions currently open in iTerm2, including the buried ones. Variables are looked up with
+// iTerm2 when first needed.
+func (a *App) SessionStates() ([]*SessionState, error) {
+	resp, err := a.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("session states: %w", err)
+	}
+
+	focusNotifications, err := a.GetFocus()
+	if err != nil {
+		return nil, fmt.Errorf("session states: %w", err)
+	}
+
+	focus := newFocusState(focusNotifications)
+
+	var states []*SessionState
+
+	for _, w := range resp.GetWindows() {
+		for _, t := range w.GetTabs() {
+			for _, summary := range splitTreeSessions(t.GetRoot()) {
+				state := a.newSessionState(summary)
+				state.WindowId = w.GetWindowId()
+				state.TabId = t.GetTabId()
+				state.Focused = focus.focused(state.WindowId, state.TabId, state.SessionId)
+				states = append(states, state)
+			}
+		}
+	}
+
+	for _, summary := range resp.GetBuriedSessions() {
+		state := a.newSessionState(summary)
+		state.Buried = true
+		states = append(states, state)
+	}
+
+	return states, nil
+}
+
+// Snapshot captures the current state of all sessions, together with the values of the given variables, so that it
+// can be queried later without talking to iTerm2. Sessions that close while the variables are fetched are left out.
+func (a *App) Snapshot(variables ...string) (Snapshot, error) {
+	states, err := a.SessionStates()
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %w", err)
+	}
+
+	var snapshot Snapshot
+
+	for _, state := range states {
+		state.Variables = make(map[string]string)
+
+		if len(variables) > 0 {
+			values, err := a.SessionById(state.SessionId).getVariables(variables...)
+			if errors.Is(err, ErrSessionNotFound) {
+				// the session closed since it was listed
+				continue
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("snapshot: %s: %w", state.SessionId, err)
+			}
+
+			for i, name := range variables {
+				if state.Variables[name], err = variableString(values[i]); err != nil {
+					return nil, fmt.Errorf("snapshot: %s: %w", state.SessionId, err)
+				}
+			}
+		}
+
+		state.lookup = nil
+		snapshot = append(snapshot, state)
+	}
+
+	return snapshot, nil
+}
+
+// Sessions starts a SessionQuery over the sessions currently open in iTerm2.
+func (a *App) Sessions() *SessionQuery {
+	return NewSessionQuery(a)
+}
+
+func (a *App) newSessionState(summary *iterm2.SessionSummary) *SessionState {
+	session := a.SessionById(summary.GetUniqueIdentifier())
+
+	return &SessionState{
+		SessionId: summary.GetUniqueIdentifier(),
+		Title:     summary.GetTitle(),
+		lookup: func(name string) (string, error) {
+			values, err := session.getVariables(name)
+			if err != nil {
+				return "", fmt.Errorf("%q: %w", name, err)
+			}
+
+			return variableString(values[0])
+		},
+	}
+}
+
+// focusState tells which session has keyboard focus, from the notifications of a FocusResponse.
+type focusState struct {
+	currentWindow  string
+	selectedTabs   map[string]bool
+	activeSessions map[string]bool
+}
+
+func newFocusState(notifications []*iterm2.FocusChangedNotification) focusState {
+	f := focusState{selectedTabs: make(map[string]bool), activeSessions: make(map[string]bool)}
+
+	for _, n := range notifications {
+		update := GetFocusUpdate(n)
+
+		switch update.Which {
+		case WindowBecameKey, WindowIsCurrent:
+			f.currentWindow = update.Id
+		case TabSelected:
+			f.selectedTabs[update.Id] = true
+		case SessionSelected:
+			f.activeSessions[update.Id] = true
+		}
+	}
+
+	return f
+}
+
+func (f focusState) focused(windowId, tabId, sessionId string) bool {
+	return windowId == f.currentWindow && f.selectedTabs[tabId] && f.activeSessions[sessionId]
+}
+
+// A SessionPredicate tells whether a session is matched by a SessionQuery.
+type SessionPredicate func(s *SessionState) (bool, error)
+
+// SessionQuery selects sessions from a SessionSource. Methods such as Where and InWindow return a new, narrower
+// query, leaving the original one untouched.
+type SessionQuery struct {
+	source        SessionSource
+	predicates    []SessionPredicate
+	currentWindow bool
+}
+
+// NewSessionQuery creates a SessionQuery matching all the sessions of the given source.
+func NewSessionQuery(source SessionSource) *SessionQuery {
+	return &SessionQuery{source: source}
+}
+
+// Where narrows the query to sessions matching all the given predicates.
+func (q *SessionQuery) Where(predicates ...SessionPredicate) *SessionQuery {
+	narrower := &SessionQuery{source: q.source, currentWindow: q.currentWindow}
+	narrower.predicates = append(append(narrower.predicates, q.predicates...), predicates...)
+	return narrower
+}
+
+// InWindow narrows the query to sessions in the given window.
+func (q *SessionQuery) InWindow(windowId string) *SessionQuery {
+	return q.Where(func(s *SessionState) (bool, error) {
+		return s.WindowId == windowId, nil
+	})
+}
+
+// InTab narrows the query to sessions in the given tab.
+func (q *SessionQuery) InTab(tabId string) *SessionQuery {
+	return q.Where(func(s *SessionState) (bool, error) {
+		return s.TabId == tabId, nil
+	})
+}
+
+// InCurrentWindow narrows the query to sessions in the window of the focused session.
+func (q *SessionQuery) InCurrentWindow() *SessionQuery {
+	narrower := q.Where()
+	narrower.currentWindow = true
+	return narrower
+}
+
+// Select narrows the query with a selector, a space separated list of terms in the form `key op value`, all of
+// which must match. Supported operators are `=`, `!=`, `^=` (has prefix), `*=` (contains) and `~=` (matches the
+// regular expression). Values can be double quoted, Go-style, when they contain spaces. The keys are:
+//
+//	id, window, tab, title    the session's ID, window ID, tab ID and title; use window=current for InCurrentWindow
+//	buried, focused           true or false, only with = and !=
+//	profile, job, host        shorthands for the profileName, jobName and hostname variables
+//	cwd                       the path variable is the value or a directory under it, only with = and !=
+//	anything else             the named session variable, such as path or user.gitBranch
+//
+// For example: `profile="Dev Box" job=vim window=current` or `cwd=~/src/foo user.gitBranch!=main`.
+func (q *SessionQuery) Select(selector string) (*SessionQuery, error) {
+	terms, err := parseSelector(selector)
+	if err != nil {
+		return nil, fmt.Errorf("select: %w", err)
+	}
+
+	narrower := q.Where()
+
+	for _, term := range terms {
+		if term.key == "window" && term.op == "=" && term.value == "current" {
+			narrower.currentWindow = true
+			continue
+		}
+
+		predicate, err := term.predicate()
+		if err != nil {
+			return nil, fmt.Errorf("select: %w", err)
+		}
+
+		narrower.predicates = append(narrower.predicates, predicate)
+	}
+
+	return narrower, nil
+}
+
+// States runs the query and returns the states of the matching sessions.
+func (q *SessionQuery) States() ([]*SessionState, error) {
+	states, err := q.source.SessionStates()
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+
+	predicates := q.predicates
+
+	if q.currentWindow {
+		currentWindow := ""
+		for _, s := range states {
+			if s.Focused {
+				currentWindow = s.WindowId
+			}
+		}
+
+		predicates = append([]SessionPredicate{func(s *SessionState) (bool, error) {
+			return currentWindow != "" && s.WindowId == currentWindow, nil
+		}}, predicates...)
+	}
+
+	var matches []*SessionState
+
+	for _, s := range states {
+		ok, err := AllOf(predicates...)(s)
+		if err != nil {
+			return nil, fmt.Errorf("query: %s: %w", s.SessionId, err)
+		}
+
+		if ok {
+			matches = append(matches, s)
+		}
+	}
+
+	return matches, nil
+}
+
+// Ids runs the query and returns the IDs of the matching sessions. Use App.SessionById to get a Session from its ID.
+func (q *SessionQuery) Ids() ([]string, error) {
+	states, err := q.States()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, s := range states {
+		ids = append(ids, s.SessionId)
+	}
+
+	return ids, nil
+}
+
+// AllOf matches sessions matched by all the given predicates.
+func AllOf(predicates ...SessionPredicate) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		for _, p := range predicates {
+			if ok, err := p(s); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// AnyOf matches sessions matched by at least one of the given predicates.
+func AnyOf(predicates ...SessionPredicate) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		for _, p := range predicates {
+			if ok, err := p(s); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// Not matches sessions not matched by the given predicate.
+func Not(predicate SessionPredicate) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		ok, err := predicate(s)
+		return !ok && err == nil, err
+	}
+}
+
+// TitleIs matches sessions with the given title.
+func TitleIs(title string) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		return s.Title == title, nil
+	}
+}
+
+// TitleMatches matches sessions whose title matches the regular expression.
+func TitleMatches(re *regexp.Regexp) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		return re.MatchString(s.Title), nil
+	}
+}
+
+// IsBuried matches buried sessions, or sessions that are not buried when buried is false.
+func IsBuried(buried bool) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		return s.Buried == buried, nil
+	}
+}
+
+// IsFocused matches the session with keyboard focus, or all the others when focused is false.
+func IsFocused(focused bool) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		return s.Focused == focused, nil
+	}
+}
+
+// VariableIs matches sessions where the named variable has the given value. See SessionState.Variable.
+func VariableIs(name, value string) SessionPredicate {
+	return variablePredicate(name, func(v string) bool {
+		return v == value
+	})
+}
+
+// VariableHasPrefix matches sessions where the named variable starts with the given prefix.
+func VariableHasPrefix(name, prefix string) SessionPredicate {
+	return variablePredicate(name, func(v string) bool {
+		return strings.HasPrefix(v, prefix)
+	})
+}
+
+// VariableMatches matches sessions where the named variable matches the regular expression.
+func VariableMatches(name string, re *regexp.Regexp) SessionPredicate {
+	return variablePredicate(name, re.MatchString)
+}
+
+// ProfileIs matches sessions using the named profile.
+func ProfileIs(name string) SessionPredicate {
+	return VariableIs("profileName", name)
+}
+
+// JobIs matches sessions whose foreground job has the given name, such as "vim".
+func JobIs(name string) SessionPredicate {
+	return VariableIs("jobName", name)
+}
+
+// HostIs matches sessions whose current host name, as reported by shell integration, is the given one.
+func HostIs(hostname string) SessionPredicate {
+	return VariableIs("hostname", hostname)
+}
+
+// PathUnder matches sessions whose current directory is dir, or any directory under it. A leading ~ in dir is
+// expanded to the user's home directory.
+func PathUnder(dir string) SessionPredicate {
+	expanded, err := homedir.Expand(dir)
+	if err == nil {
+		dir = filepath.Clean(expanded)
+	}
+
+	return func(s *SessionState) (bool, error) {
+		if err != nil {
+			return false, fmt.Errorf("path under %q: %w", dir, err)
+		}
+
+		path, err := s.Variable("path")
+		if err != nil {
+			return false, err
+		}
+
+		path = filepath.Clean(path)
+		return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, "/")+"/"), nil
+	}
+}
+
+func variablePredicate(name string, match func(string) bool) SessionPredicate {
+	return func(s *SessionState) (bool, error) {
+		v, err := s.Variable(name)
+		if err != nil {
+			return false, err
+		}
+		return match(v), nil
+	}
+}
+
+var selectorKeyAndOp = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.]*)(!=|\^=|\*=|~=|=)`)
+
+var selectorAliases = map[string]string{
+	"profile": "profileName",
+	"job":     "jobName",
+	"host":    "hostname",
+}
+
+type selectorTerm struct {
+	key   string
+	op    string
+	value string
+}
+
+func parseSelector(selector string) ([]selectorTerm, error) {
+	var terms []selectorTerm
+
+	rest := strings.TrimSpace(selector)
+	for rest != "" {
+		m := selectorKeyAndOp.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("expected `key op value` at %q", rest)
+		}
+
+		term := selectorTerm{key: m[1], op: m[2]}
+		rest = rest[len(m[0]):]
+
+		var err error
+		if term.value, rest, err = parseSelectorValue(rest); err != nil {
+			return nil, fmt.Errorf("%s%s: %w", term.key, term.op, err)
+		}
+
+		terms = append(terms, term)
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+	}
+
+	return terms, nil
+}
+
+func parseSelectorValue(s string) (value string, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		if end < 0 {
+			end = len(s)
+		}
+		return s[:end], s[end:], nil
+	}
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err = strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+
+	return "", "", fmt.Errorf("unterminated quoted value %s", s)
+}
+
+func (t selectorTerm) predicate() (SessionPredicate, error) {
+	switch t.key {
+	case "buried", "focused":
+		return t.boolPredicate()
+	case "cwd":
+		if t.op != "=" && t.op != "!=" {
+			return nil, fmt.Errorf("cwd: unsupported operator %s", t.op)
+		}
+		return t.negateIf(t.op == "!=", PathUnder(t.value)), nil
+	}
+
+	match, err := t.stringMatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var field func(s *SessionState) (string, error)
+
+	switch t.key {
+	case "id":
+		field = func(s *SessionState) (string, error) { return s.SessionId, nil }
+	case "window":
+		field = func(s *SessionState) (string, error) { return s.WindowId, nil }
+	case "tab":
+		field = func(s *SessionState) (string, error) { return s.TabId, nil }
+	case "title":
+		field = func(s *SessionState) (string, error) { return s.Title, nil }
+	default:
+		name := t.key
+		if alias, ok := selectorAliases[name]; ok {
+			name = alias
+		}
+		field = func(s *SessionState) (string, error) { return s.Variable(name) }
+	}
+
+	return func(s *SessionState) (bool, error) {
+		v, err := field(s)
+		if err != nil {
+			return false, err
+		}
+		return match(v), nil
+	}, nil
+}
+
+func (t selectorTerm) boolPredicate() (SessionPredicate, error) {
+	if t.op != "=" && t.op != "!=" {
+		return nil, fmt.Errorf("%s: unsupported operator %s", t.key, t.op)
+	}
+
+	value, err := strconv.ParseBool(t.value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", t.key, err)
+	}
+
+	if t.key == "buried" {
+		return t.negateIf(t.op == "!=", IsBuried(value)), nil
+	}
+
+	return t.negateIf(t.op == "!=", IsFocused(value)), nil
+}
+
+func (t selectorTerm) negateIf(negate bool, p SessionPredicate) SessionPredicate {
+	if negate {
+		return Not(p)
+	}
+	return p
+}
+
+func (t selectorTerm) stringMatcher() (func(string) bool, error) {
+	switch t.op {
+	case "=":
+		return func(v string) bool { return v == t.value }, nil
+	case "!=":
+		return func(v string) bool { return v != t.value }, nil
+	case "^=":
+		return func(v string) bool { return strings.HasPrefix(v, t.value) }, nil
+	case "*=":
+		return func(v string) bool { return strings.Contains(v, t.value) }, nil
+	case "~=":
+		re, err := regexp.Compile(t.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", t.key, err)
+		}
+		return re.MatchString, nil
+	}
+
+	return nil, fmt.Errorf("%s: unsupported operator %s", t.key, t.op)
+}
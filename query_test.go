@@ -0,0 +1,149 @@
+package itermctl_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func testSnapshot() itermctl.Snapshot {
+	return itermctl.Snapshot{
+		{
+			SessionId: "s1", WindowId: "w1", TabId: "t1", Title: "vim main.go", Focused: true,
+			Variables: map[string]string{"profileName": "Default", "jobName": "vim", "path": "/home/me/src/foo",
+				"user.gitBranch": "main"},
+		},
+		{
+			SessionId: "s2", WindowId: "w1", TabId: "t2", Title: "zsh",
+			Variables: map[string]string{"profileName": "Dev Box", "jobName": "zsh", "path": "/home/me/src/foo/cmd",
+				"user.gitBranch": "feature"},
+		},
+		{
+			SessionId: "s3", WindowId: "w2", TabId: "t3", Title: "vim notes.md",
+			Variables: map[string]string{"profileName": "Default", "jobName": "vim", "path": "/home/me/src/foobar",
+				"user.gitBranch": ""},
+		},
+		{
+			SessionId: "s4", Title: "logs", Buried: true,
+			Variables: map[string]string{"profileName": "Default", "jobName": "tail", "path": "/var/log",
+				"user.gitBranch": ""},
+		},
+	}
+}
+
+func TestSessionQuery_Where(t *testing.T) {
+	query := itermctl.NewSessionQuery(testSnapshot())
+
+	examples := []struct {
+		name     string
+		query    *itermctl.SessionQuery
+		expected []string
+	}{
+		{name: "all", query: query, expected: []string{"s1", "s2", "s3", "s4"}},
+		{name: "in window", query: query.InWindow("w1"), expected: []string{"s1", "s2"}},
+		{name: "in tab", query: query.InTab("t3"), expected: []string{"s3"}},
+		{name: "in current window", query: query.InCurrentWindow(), expected: []string{"s1", "s2"}},
+		{name: "profile", query: query.Where(itermctl.ProfileIs("Default")), expected: []string{"s1", "s3", "s4"}},
+		{name: "job", query: query.InWindow("w1").Where(itermctl.JobIs("vim")), expected: []string{"s1"}},
+		{name: "path under", query: query.Where(itermctl.PathUnder("/home/me/src/foo/")), expected: []string{"s1", "s2"}},
+		{name: "buried", query: query.Where(itermctl.IsBuried(true)), expected: []string{"s4"}},
+		{name: "focused", query: query.Where(itermctl.IsFocused(true)), expected: []string{"s1"}},
+		{
+			name:     "title matches",
+			query:    query.Where(itermctl.TitleMatches(regexp.MustCompile(`^vim `))),
+			expected: []string{"s1", "s3"},
+		},
+		{
+			name:     "any of",
+			query:    query.Where(itermctl.AnyOf(itermctl.JobIs("tail"), itermctl.TitleIs("zsh"))),
+			expected: []string{"s2", "s4"},
+		},
+		{
+			name:     "not",
+			query:    query.Where(itermctl.Not(itermctl.VariableIs("user.gitBranch", ""))),
+			expected: []string{"s1", "s2"},
+		},
+	}
+
+	for _, example := range examples {
+		t.Run(example.name, func(t *testing.T) {
+			ids, err := example.query.Ids()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ids, example.expected) {
+				t.Fatalf("expected %v, got %v", example.expected, ids)
+			}
+		})
+	}
+}
+
+func TestSessionQuery_Select(t *testing.T) {
+	examples := []struct {
+		selector string
+		expected []string
+	}{
+		{selector: "", expected: []string{"s1", "s2", "s3", "s4"}},
+		{selector: "window=w1", expected: []string{"s1", "s2"}},
+		{selector: "window=current job=vim", expected: []string{"s1"}},
+		{selector: `profile="Dev Box"`, expected: []string{"s2"}},
+		{selector: "profile!=Default", expected: []string{"s2"}},
+		{selector: "cwd=/home/me/src/foo", expected: []string{"s1", "s2"}},
+		{selector: "cwd!=/home/me/src/foo buried=false", expected: []string{"s3"}},
+		{selector: "path^=/home/me/src/foo", expected: []string{"s1", "s2", "s3"}},
+		{selector: "title*=notes", expected: []string{"s3"}},
+		{selector: `title~="^vim (main|notes)\\."`, expected: []string{"s1", "s3"}},
+		{selector: "user.gitBranch=feature", expected: []string{"s2"}},
+		{selector: "focused=true", expected: []string{"s1"}},
+		{selector: "buried=true job=vim", expected: nil},
+	}
+
+	for _, example := range examples {
+		t.Run(example.selector, func(t *testing.T) {
+			query, err := itermctl.NewSessionQuery(testSnapshot()).Select(example.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids, err := query.Ids()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ids, example.expected) {
+				t.Fatalf("expected %v, got %v", example.expected, ids)
+			}
+		})
+	}
+}
+
+func TestSessionQuery_Select_Errors(t *testing.T) {
+	selectors := []string{
+		"window",
+		"=foo",
+		`title="unterminated`,
+		"buried=maybe",
+		"buried^=t",
+		"cwd~=foo",
+		"title~=(",
+	}
+
+	for _, selector := range selectors {
+		t.Run(selector, func(t *testing.T) {
+			if _, err := itermctl.NewSessionQuery(testSnapshot()).Select(selector); err == nil {
+				t.Fatalf("expected an error for %q", selector)
+			}
+		})
+	}
+}
+
+func TestSessionQuery_VariableNotCached(t *testing.T) {
+	_, err := itermctl.NewSessionQuery(testSnapshot()).Where(itermctl.HostIs("example.com")).Ids()
+
+	if !errors.Is(err, itermctl.ErrVariableNotCached) {
+		t.Fatalf("expected %v, got %v", itermctl.ErrVariableNotCached, err)
+	}
+}
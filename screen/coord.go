@@ -0,0 +1,115 @@
+package screen
+
+import "mrz.io/itermctl/iterm2"
+
+// Coord is a cell position. Y is the absolute line number, counted from the first line ever added to the history: it
+// stays the same when old lines are dropped from the scrollback, see Viewport to convert it to a row of the screen.
+type Coord struct {
+	X int32
+	Y int64
+}
+
+// CoordRange is the range of cells from Start to End, End excluded, in reading order.
+type CoordRange struct {
+	Start Coord
+	End   Coord
+}
+
+// ColumnRange is a window of Length columns starting at Location.
+type ColumnRange struct {
+	Location int64
+	Length   int64
+}
+
+// WindowedCoordRange is a CoordRange restricted to a window of columns on each line; nil Columns means all the
+// columns.
+type WindowedCoordRange struct {
+	CoordRange
+	Columns *ColumnRange
+}
+
+// NewCoord converts an iterm2.Coord.
+func NewCoord(c *iterm2.Coord) Coord {
+	return Coord{X: c.GetX(), Y: c.GetY()}
+}
+
+// NewCoordRange converts an iterm2.CoordRange.
+func NewCoordRange(r *iterm2.CoordRange) CoordRange {
+	return CoordRange{Start: NewCoord(r.GetStart()), End: NewCoord(r.GetEnd())}
+}
+
+// NewWindowedCoordRange converts an iterm2.WindowedCoordRange.
+func NewWindowedCoordRange(r *iterm2.WindowedCoordRange) WindowedCoordRange {
+	windowed := WindowedCoordRange{CoordRange: NewCoordRange(r.GetCoordRange())}
+
+	if columns := r.GetColumns(); columns != nil {
+		windowed.Columns = &ColumnRange{Location: columns.GetLocation(), Length: columns.GetLength()}
+	}
+
+	return windowed
+}
+
+// LineRange returns the range of the whole lines from start to end, end excluded.
+func LineRange(start, end int64) CoordRange {
+	return CoordRange{Start: Coord{Y: start}, End: Coord{Y: end}}
+}
+
+// Proto converts the Coord to an iterm2.Coord.
+func (c Coord) Proto() *iterm2.Coord {
+	return &iterm2.Coord{X: &c.X, Y: &c.Y}
+}
+
+// Before returns true if c comes before other in reading order.
+func (c Coord) Before(other Coord) bool {
+	return c.Y < other.Y || c.Y == other.Y && c.X < other.X
+}
+
+// Proto converts the CoordRange to an iterm2.CoordRange.
+func (r CoordRange) Proto() *iterm2.CoordRange {
+	return &iterm2.CoordRange{Start: r.Start.Proto(), End: r.End.Proto()}
+}
+
+// Contains returns true if c is in the range.
+func (r CoordRange) Contains(c Coord) bool {
+	return !c.Before(r.Start) && c.Before(r.End)
+}
+
+// Windowed returns the CoordRange as a WindowedCoordRange over all the columns.
+func (r CoordRange) Windowed() WindowedCoordRange {
+	return WindowedCoordRange{CoordRange: r}
+}
+
+// Proto converts the WindowedCoordRange to an iterm2.WindowedCoordRange.
+func (r WindowedCoordRange) Proto() *iterm2.WindowedCoordRange {
+	windowed := &iterm2.WindowedCoordRange{CoordRange: r.CoordRange.Proto()}
+
+	if r.Columns != nil {
+		columns := *r.Columns
+		windowed.Columns = &iterm2.Range{Location: &columns.Location, Length: &columns.Length}
+	}
+
+	return windowed
+}
+
+// Viewport is the position of the screen in the scrollback, when it's scrolled to the bottom.
+type Viewport struct {
+	// FirstLine is the absolute line number of the first row of the screen, that is the number of lines above the
+	// screen including those dropped from the scrollback.
+	FirstLine int64
+	// Height is the number of rows of the screen.
+	Height int32
+}
+
+// Row converts an absolute line number to a row of the screen; it returns false if the line is not on the screen.
+func (v Viewport) Row(y int64) (int32, bool) {
+	if y < v.FirstLine || y >= v.FirstLine+int64(v.Height) {
+		return 0, false
+	}
+
+	return int32(y - v.FirstLine), true
+}
+
+// Line converts a row of the screen to an absolute line number.
+func (v Viewport) Line(row int32) int64 {
+	return v.FirstLine + int64(row)
+}
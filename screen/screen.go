@@ -0,0 +1,195 @@
+// Package screen models the contents of a session's screen and scrollback as a grid of cells, mapping text offsets to
+// cell coordinates and back.
+package screen
+
+import (
+	"mrz.io/itermctl/iterm2"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Cell is a cell of a Line.
+type Cell struct {
+	// Text is the code points shown in the cell: a character and its combining marks. It's empty for uninitialized
+	// cells, and for the right half of wide characters.
+	Text string
+	// Offset is the byte offset of Text in the Line's Text.
+	Offset int
+}
+
+// Line is a line of the screen or scrollback.
+type Line struct {
+	// Y is the absolute line number.
+	Y    int64
+	Text string
+	// Cells are the cells of the line, trailing uninitialized cells excluded.
+	Cells []Cell
+	// SoftWrapped is true if the line continues on the next one, without a line break.
+	SoftWrapped bool
+}
+
+// NewLine builds the cells of line number y, following the algorithm documented with LineContents' code_points_per_cell.
+func NewLine(y int64, contents *iterm2.LineContents) Line {
+	line := Line{
+		Y:           y,
+		Text:        contents.GetText(),
+		SoftWrapped: contents.GetContinuation() == iterm2.LineContents_CONTINUATION_SOFT_EOL,
+	}
+
+	offset := 0
+	for _, cpc := range contents.GetCodePointsPerCell() {
+		for i := int32(0); i < cpc.GetRepeats(); i++ {
+			start := offset
+			for n := int32(0); n < cpc.GetNumCodePoints() && offset < len(line.Text); n++ {
+				_, size := utf8.DecodeRuneInString(line.Text[offset:])
+				offset += size
+			}
+
+			line.Cells = append(line.Cells, Cell{Text: line.Text[start:offset], Offset: start})
+		}
+	}
+
+	return line
+}
+
+// Column returns the column of the cell showing the byte at the given offset of the line's Text. The offset right after
+// the Text maps to the column after the last cell. It returns false if the offset is out of the Text.
+func (l Line) Column(offset int) (int32, bool) {
+	if offset == len(l.Text) {
+		return int32(len(l.Cells)), true
+	}
+
+	for x, cell := range l.Cells {
+		if offset >= cell.Offset && offset < cell.Offset+len(cell.Text) {
+			return int32(x), true
+		}
+	}
+
+	return 0, false
+}
+
+// Offset returns the byte offset, in the line's Text, of the cell at the given column. Columns after the last cell
+// map to the end of the Text.
+func (l Line) Offset(x int32) int {
+	if x < 0 {
+		return 0
+	}
+
+	if int(x) >= len(l.Cells) {
+		return len(l.Text)
+	}
+
+	return l.Cells[x].Offset
+}
+
+// Screen is a range of lines, as returned by a GetBufferRequest.
+type Screen struct {
+	Lines  []Line
+	Cursor Coord
+	// Text is the text of all the Lines, each one followed by a line break unless it's soft-wrapped.
+	Text string
+
+	offsets []int
+}
+
+// New builds a Screen from a GetBufferResponse, numbering its lines from the start of the returned range.
+func New(resp *iterm2.GetBufferResponse) *Screen {
+	y := resp.GetWindowedCoordRange().GetCoordRange().GetStart().GetY()
+	if resp.GetWindowedCoordRange() == nil {
+		y = resp.GetRange().GetLocation()
+	}
+
+	return NewAt(resp, y)
+}
+
+// NewAt builds a Screen from a GetBufferResponse, whose first line is the absolute line number y.
+func NewAt(resp *iterm2.GetBufferResponse, y int64) *Screen {
+	s := &Screen{Cursor: NewCoord(resp.GetCursor())}
+	text := &strings.Builder{}
+
+	for i, contents := range resp.GetContents() {
+		line := NewLine(y+int64(i), contents)
+		s.Lines = append(s.Lines, line)
+		s.offsets = append(s.offsets, text.Len())
+
+		text.WriteString(line.Text)
+		if !line.SoftWrapped {
+			text.WriteString("\n")
+		}
+	}
+
+	s.Text = text.String()
+	return s
+}
+
+// Line returns the line with the given absolute line number, or false if it's not part of the Screen.
+func (s *Screen) Line(y int64) (Line, bool) {
+	if len(s.Lines) == 0 || y < s.Lines[0].Y || y >= s.Lines[0].Y+int64(len(s.Lines)) {
+		return Line{}, false
+	}
+
+	return s.Lines[y-s.Lines[0].Y], true
+}
+
+// Coord returns the coordinates of the cell showing the byte at the given offset of the Screen's Text. Line breaks map
+// to the column after the last cell of their line, and the end of the Text maps to the column after the last cell of
+// the last line. It returns false if the offset is out of the Text.
+func (s *Screen) Coord(offset int) (Coord, bool) {
+	if offset < 0 || offset > len(s.Text) || len(s.Lines) == 0 {
+		return Coord{}, false
+	}
+
+	i := sort.Search(len(s.offsets), func(i int) bool { return s.offsets[i] > offset }) - 1
+	line := s.Lines[i]
+
+	lineOffset := offset - s.offsets[i]
+	if lineOffset > len(line.Text) {
+		lineOffset = len(line.Text)
+	}
+
+	x, ok := line.Column(lineOffset)
+	return Coord{X: x, Y: line.Y}, ok
+}
+
+// Offset returns the byte offset, in the Screen's Text, of the cell at the given coordinates, or false if the line is
+// not part of the Screen.
+func (s *Screen) Offset(c Coord) (int, bool) {
+	line, ok := s.Line(c.Y)
+	if !ok {
+		return 0, false
+	}
+
+	return s.offsets[c.Y-s.Lines[0].Y] + line.Offset(c.X), true
+}
+
+// CoordRange returns the cells showing the bytes of the Screen's Text from start to end, end excluded; for example, a
+// match of a regexp.Regexp. It returns false if the offsets are out of the Text.
+func (s *Screen) CoordRange(start, end int) (CoordRange, bool) {
+	startCoord, ok := s.Coord(start)
+	if !ok {
+		return CoordRange{}, false
+	}
+
+	if end == start {
+		return CoordRange{Start: startCoord, End: startCoord}, true
+	}
+
+	// the last byte of the range determines the last cell, which may show more code points
+	last, ok := s.Coord(end - 1)
+	if !ok {
+		return CoordRange{}, false
+	}
+
+	if line, _ := s.Line(last.Y); int(last.X) == len(line.Cells) {
+		// the range ends with a line break
+		return CoordRange{Start: startCoord, End: Coord{X: 0, Y: last.Y + 1}}, true
+	}
+
+	return CoordRange{Start: startCoord, End: Coord{X: last.X + 1, Y: last.Y}}, true
+}
+
+// CursorRow returns the row of the cursor in the given Viewport, or false if it's not on the screen.
+func (s *Screen) CursorRow(v Viewport) (int32, bool) {
+	return v.Row(s.Cursor.Y)
+}
@@ -0,0 +1,172 @@
+package screen
+
+import (
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func cells(spec ...int32) []*iterm2.CodePointsPerCell {
+	var cpc []*iterm2.CodePointsPerCell
+	for i := 0; i < len(spec); i += 2 {
+		numCodePoints, repeats := spec[i], spec[i+1]
+		cpc = append(cpc, &iterm2.CodePointsPerCell{NumCodePoints: &numCodePoints, Repeats: &repeats})
+	}
+	return cpc
+}
+
+func testScreen() *Screen {
+	soft := iterm2.LineContents_CONTINUATION_SOFT_EOL
+	line1 := "xyzcompan\u0303i\u0301a"
+	line2 := "漢x"
+	x, y := int32(2), int64(11)
+	start, end := int64(10), int64(12)
+
+	return New(&iterm2.GetBufferResponse{
+		WindowedCoordRange: &iterm2.WindowedCoordRange{
+			CoordRange: &iterm2.CoordRange{
+				Start: &iterm2.Coord{Y: &start},
+				End:   &iterm2.Coord{Y: &end},
+			},
+		},
+		Contents: []*iterm2.LineContents{
+			{Text: &line1, CodePointsPerCell: cells(1, 3, 0, 1, 1, 5, 2, 2, 1, 1), Continuation: &soft},
+			{Text: &line2, CodePointsPerCell: cells(1, 1, 0, 1, 1, 1)},
+		},
+		Cursor: &iterm2.Coord{X: &x, Y: &y},
+	})
+}
+
+func TestNewLine(t *testing.T) {
+	s := testScreen()
+
+	if s.Text != "xyzcompan\u0303i\u0301a漢x\n" {
+		t.Fatalf("unexpected text %q", s.Text)
+	}
+
+	var texts []string
+	for _, cell := range s.Lines[0].Cells {
+		texts = append(texts, cell.Text)
+	}
+
+	expected := []string{"x", "y", "z", "", "c", "o", "m", "p", "a", "n\u0303", "i\u0301", "a"}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Fatalf("expected cells %q, got %q", expected, texts)
+	}
+
+	if !s.Lines[0].SoftWrapped || s.Lines[1].SoftWrapped {
+		t.Fatal("expected only the first line to be soft-wrapped")
+	}
+
+	if s.Lines[1].Y != 11 || len(s.Lines[1].Cells) != 3 {
+		t.Fatalf("unexpected second line %+v", s.Lines[1])
+	}
+}
+
+func TestScreen_Coord(t *testing.T) {
+	s := testScreen()
+
+	tests := []struct {
+		offset   int
+		expected Coord
+	}{
+		{0, Coord{X: 0, Y: 10}},
+		{strings.Index(s.Text, "c"), Coord{X: 4, Y: 10}},
+		{strings.Index(s.Text, "\u0301"), Coord{X: 10, Y: 10}},
+		{strings.Index(s.Text, "漢"), Coord{X: 0, Y: 11}},
+		{strings.Index(s.Text, "漢") + 1, Coord{X: 0, Y: 11}},
+		{strings.Index(s.Text, "x\n"), Coord{X: 2, Y: 11}},
+		{len(s.Text) - 1, Coord{X: 3, Y: 11}},
+	}
+
+	for _, test := range tests {
+		c, ok := s.Coord(test.offset)
+		if !ok || c != test.expected {
+			t.Errorf("offset %d: expected %v, got %v (%v)", test.offset, test.expected, c, ok)
+		}
+	}
+
+	if _, ok := s.Coord(len(s.Text) + 1); ok {
+		t.Error("expected an offset out of the text to fail")
+	}
+}
+
+func TestScreen_Offset(t *testing.T) {
+	s := testScreen()
+
+	tests := []struct {
+		coord    Coord
+		expected int
+	}{
+		{Coord{X: 3, Y: 10}, strings.Index(s.Text, "c")},
+		{Coord{X: 11, Y: 10}, strings.Index(s.Text, "a漢")},
+		{Coord{X: 1, Y: 11}, strings.Index(s.Text, "x\n")},
+		{Coord{X: 80, Y: 11}, len(s.Text) - 1},
+	}
+
+	for _, test := range tests {
+		offset, ok := s.Offset(test.coord)
+		if !ok || offset != test.expected {
+			t.Errorf("%v: expected %d, got %d (%v)", test.coord, test.expected, offset, ok)
+		}
+	}
+
+	if _, ok := s.Offset(Coord{Y: 12}); ok {
+		t.Error("expected a line out of the screen to fail")
+	}
+}
+
+func TestScreen_CoordRange(t *testing.T) {
+	s := testScreen()
+
+	tests := []struct {
+		pattern  string
+		expected CoordRange
+	}{
+		{"compa", CoordRange{Start: Coord{X: 4, Y: 10}, End: Coord{X: 9, Y: 10}}},
+		{"i\u0301a漢", CoordRange{Start: Coord{X: 10, Y: 10}, End: Coord{X: 1, Y: 11}}},
+		{"x\n", CoordRange{Start: Coord{X: 2, Y: 11}, End: Coord{X: 0, Y: 12}}},
+	}
+
+	for _, test := range tests {
+		loc := regexp.MustCompile(test.pattern).FindStringIndex(s.Text)
+		r, ok := s.CoordRange(loc[0], loc[1])
+		if !ok || r != test.expected {
+			t.Errorf("%q: expected %v, got %v (%v)", test.pattern, test.expected, r, ok)
+		}
+	}
+}
+
+func TestViewport(t *testing.T) {
+	s := testScreen()
+	v := Viewport{FirstLine: 10, Height: 24}
+
+	if row, ok := s.CursorRow(v); !ok || row != 1 {
+		t.Fatalf("expected the cursor on row 1, got %d (%v)", row, ok)
+	}
+
+	if _, ok := v.Row(34); ok {
+		t.Fatal("expected line 34 to be below the screen")
+	}
+
+	if y := v.Line(23); y != 33 {
+		t.Fatalf("expected row 23 to be line 33, got %d", y)
+	}
+}
+
+func TestWindowedCoordRange_Proto(t *testing.T) {
+	r := WindowedCoordRange{
+		CoordRange: CoordRange{Start: Coord{X: 1, Y: 2}, End: Coord{X: 3, Y: 4}},
+		Columns:    &ColumnRange{Location: 1, Length: 2},
+	}
+
+	if converted := NewWindowedCoordRange(r.Proto()); !reflect.DeepEqual(converted, r) {
+		t.Fatalf("expected %v, got %v", r, converted)
+	}
+
+	if !r.Contains(Coord{X: 80, Y: 2}) || r.Contains(Coord{X: 3, Y: 4}) {
+		t.Fatal("unexpected range containment")
+	}
+}
@@ -2,12 +2,22 @@ package itermctl
 
 import (
 	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
 	"mrz.io/itermctl/iterm2"
+	"mrz.io/itermctl/screen"
+	"time"
+)
+
+const (
+	DefaultScreenStreamInterval = 100 * time.Millisecond
+	DefaultScreenStreamDebounce = 20 * time.Millisecond
 )
 
 // MonitorScreenUpdates subscribes to ScreenUpdateNotification and forwards each one to the returned channel.
-// Subscription lasts until the given context is canceled or the conn's connection is closed. Use methods such as
-// App.ScreenContents to retrieve the screen's contents.
+// Subscription lasts until the given context is canceled or the conn's connection is closed, then the channel is
+// closed. Use methods such as Session.ScreenContents to retrieve the screen's contents, or a ScreenStreamer to get
+// them along with the updates.
 func MonitorScreenUpdates(ctx context.Context, conn *Connection, sessionId string) (<-chan *iterm2.ScreenUpdateNotification, error) {
 	notifications := make(chan *iterm2.ScreenUpdateNotification)
 
@@ -21,10 +31,244 @@ func MonitorScreenUpdates(ctx context.Context, conn *Connection, sessionId strin
 	go func() {
 		for msg := range recv.Ch() {
 			if msg.GetNotification().GetScreenUpdateNotification() != nil {
-				notifications <- msg.GetNotification().GetScreenUpdateNotification()
+				select {
+				case notifications <- msg.GetNotification().GetScreenUpdateNotification():
+				case <-ctx.Done():
+				}
 			}
 		}
+
+		close(notifications)
 	}()
 
 	return notifications, nil
 }
+
+// ScreenStreamerOptions configures a ScreenStreamer. Zero durations are replaced by their defaults.
+type ScreenStreamerOptions struct {
+	// Interval is the minimum time between two fetches of the screen, and the maximum time a fetch can be delayed by
+	// a burst of updates.
+	Interval time.Duration
+
+	// Debounce is how long the screen must stay unchanged after an update before it's fetched.
+	Debounce time.Duration
+}
+
+// ScreenSnapshot is the visible screen of a session at some point in time, that is the screen when scrolled to the
+// bottom.
+type ScreenSnapshot struct {
+	Time     time.Time
+	Viewport screen.Viewport
+	Screen   *screen.Screen
+}
+
+// ScreenDiff is the difference between two consecutive ScreenSnapshots.
+type ScreenDiff struct {
+	Snapshot *ScreenSnapshot
+	// ChangedRows are the rows of the screen whose line is new or changed. All rows are changed in the first diff.
+	ChangedRows []int32
+	CursorMoved bool
+	// Scrolled is the number of lines that scrolled off the top of the screen into the history.
+	Scrolled int64
+}
+
+// ScreenStreamer streams the contents of a session's screen as it changes. Create one with StreamScreen.
+type ScreenStreamer struct {
+	session *Session
+	opts    ScreenStreamerOptions
+	diffs   chan *ScreenDiff
+	cancel  context.CancelFunc
+	done    chan struct{}
+	err     error
+}
+
+// StreamScreen starts a ScreenStreamer for a session, which fetches its screen when it's updated, at most once per
+// interval, and writes the differences with the previous fetch to the Diffs channel. The first ScreenDiff holds the
+// screen at the time StreamScreen is called. Streaming lasts until the given context is done, the Connection is
+// closed, ScreenStreamer.Close is called, or fetching the screen fails.
+func StreamScreen(ctx context.Context, conn *Connection, sessionId string, opts ScreenStreamerOptions) (*ScreenStreamer, error) {
+	if opts.Interval == 0 {
+		opts.Interval = DefaultScreenStreamInterval
+	}
+
+	if opts.Debounce == 0 {
+		opts.Debounce = DefaultScreenStreamDebounce
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	notifications, err := MonitorScreenUpdates(ctx, conn, sessionId)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("stream screen: %w", err)
+	}
+
+	// coalesce screen updates, so that a slow reader doesn't hold up the Connection
+	updates := make(chan struct{}, 1)
+	go func() {
+		for range notifications {
+			select {
+			case updates <- struct{}{}:
+			default:
+			}
+		}
+
+		close(updates)
+	}()
+
+	s := &ScreenStreamer{
+		session: newSession(sessionId, nil, conn, false),
+		opts:    opts,
+		diffs:   make(chan *ScreenDiff),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go s.run(ctx, updates)
+
+	return s, nil
+}
+
+// Diffs returns the channel where ScreenDiffs are written; it's closed when streaming ends.
+func (s *ScreenStreamer) Diffs() <-chan *ScreenDiff {
+	return s.diffs
+}
+
+// Close stops streaming and waits until the Diffs channel is closed.
+func (s *ScreenStreamer) Close() {
+	s.cancel()
+	<-s.done
+}
+
+// Err returns the error that ended streaming, if any. It must be called after the Diffs channel is closed.
+func (s *ScreenStreamer) Err() error {
+	return s.err
+}
+
+func (s *ScreenStreamer) run(ctx context.Context, updates <-chan struct{}) {
+	defer close(s.done)
+	defer close(s.diffs)
+	defer s.cancel()
+
+	var previous *ScreenSnapshot
+	var lastFetch, firstPending, lastUpdate time.Time
+	var timer *time.Timer
+	var fetchCh <-chan time.Time
+	pending := true
+
+	fetchNow := make(chan time.Time, 1)
+	fetchNow <- time.Now()
+	fetchCh = fetchNow
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+
+			lastUpdate = time.Now()
+			if !pending {
+				pending = true
+				firstPending = lastUpdate
+			}
+
+			due := fetchTime(s.opts, lastUpdate, firstPending, lastFetch)
+
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.NewTimer(time.Until(due))
+			fetchCh = timer.C
+		case <-fetchCh:
+			pending = false
+			fetchCh = nil
+			lastFetch = time.Now()
+
+			snapshot, err := s.snapshot()
+			if err != nil {
+				s.err = fmt.Errorf("stream screen: %w", err)
+				return
+			}
+
+			first := previous == nil
+			diff := diffScreenSnapshots(previous, snapshot)
+			previous = snapshot
+
+			if !first && len(diff.ChangedRows) == 0 && !diff.CursorMoved && diff.Scrolled == 0 {
+				continue
+			}
+
+			select {
+			case s.diffs <- diff:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func (s *ScreenStreamer) snapshot() (*ScreenSnapshot, error) {
+	tx, err := s.session.conn.Transaction()
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := tx.End(); err != nil {
+			logrus.Errorf("stream screen: %s", err)
+		}
+	}()
+
+	viewport, err := s.session.Viewport()
+	if err != nil {
+		return nil, err
+	}
+
+	lines := screen.LineRange(viewport.FirstLine, viewport.FirstLine+int64(viewport.Height))
+	resp, err := s.session.ScreenContents(lines.Windowed().Proto())
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScreenSnapshot{Time: time.Now(), Viewport: viewport, Screen: screen.NewAt(resp, viewport.FirstLine)}, nil
+}
+
+// fetchTime returns when to fetch the screen after an update: once it stayed unchanged for the debounce delay, but no
+// later than an interval after the first update since the last fetch, and no sooner than an interval after that fetch.
+func fetchTime(opts ScreenStreamerOptions, lastUpdate, firstPending, lastFetch time.Time) time.Time {
+	due := lastUpdate.Add(opts.Debounce)
+	if maxWait := firstPending.Add(opts.Interval); due.After(maxWait) {
+		due = maxWait
+	}
+	if earliest := lastFetch.Add(opts.Interval); due.Before(earliest) {
+		due = earliest
+	}
+	return due
+}
+
+func diffScreenSnapshots(previous, current *ScreenSnapshot) *ScreenDiff {
+	diff := &ScreenDiff{Snapshot: current}
+
+	if previous == nil {
+		diff.CursorMoved = true
+		for row := range current.Screen.Lines {
+			diff.ChangedRows = append(diff.ChangedRows, int32(row))
+		}
+		return diff
+	}
+
+	diff.Scrolled = current.Viewport.FirstLine - previous.Viewport.FirstLine
+	diff.CursorMoved = current.Screen.Cursor != previous.Screen.Cursor
+
+	for row, line := range current.Screen.Lines {
+		old, ok := previous.Screen.Line(line.Y)
+		if !ok || old.Text != line.Text || old.SoftWrapped != line.SoftWrapped {
+			diff.ChangedRows = append(diff.ChangedRows, int32(row))
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,109 @@
+package itermctl
+
+import (
+	"mrz.io/itermctl/screen"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSnapshot(firstLine int64, cursor screen.Coord, lines ...string) *ScreenSnapshot {
+	s := &screen.Screen{Cursor: cursor}
+	for i, text := range lines {
+		s.Lines = append(s.Lines, screen.Line{Y: firstLine + int64(i), Text: text})
+	}
+
+	return &ScreenSnapshot{Viewport: screen.Viewport{FirstLine: firstLine, Height: int32(len(lines))}, Screen: s}
+}
+
+func TestDiffScreenSnapshots(t *testing.T) {
+	cursor := screen.Coord{X: 2, Y: 11}
+
+	wrapped := testSnapshot(10, cursor, "a", "b", "c")
+	wrapped.Screen.Lines[1].SoftWrapped = true
+
+	examples := []struct {
+		name     string
+		previous *ScreenSnapshot
+		current  *ScreenSnapshot
+		expected ScreenDiff
+	}{
+		{
+			name:     "first",
+			current:  testSnapshot(10, cursor, "a", "b", "c"),
+			expected: ScreenDiff{ChangedRows: []int32{0, 1, 2}, CursorMoved: true},
+		},
+		{
+			name:     "unchanged",
+			previous: testSnapshot(10, cursor, "a", "b", "c"),
+			current:  testSnapshot(10, cursor, "a", "b", "c"),
+			expected: ScreenDiff{},
+		},
+		{
+			name:     "changed rows",
+			previous: testSnapshot(10, cursor, "a", "b", "c"),
+			current:  testSnapshot(10, cursor, "a", "B", "C"),
+			expected: ScreenDiff{ChangedRows: []int32{1, 2}},
+		},
+		{
+			name:     "soft wrap",
+			previous: testSnapshot(10, cursor, "a", "b", "c"),
+			current:  wrapped,
+			expected: ScreenDiff{ChangedRows: []int32{1}},
+		},
+		{
+			name:     "cursor moved",
+			previous: testSnapshot(10, cursor, "a", "b", "c"),
+			current:  testSnapshot(10, screen.Coord{X: 3, Y: 11}, "a", "b", "c"),
+			expected: ScreenDiff{CursorMoved: true},
+		},
+		{
+			name:     "scrolled",
+			previous: testSnapshot(10, cursor, "a", "b", "c"),
+			current:  testSnapshot(12, cursor, "c", "d", "e"),
+			expected: ScreenDiff{ChangedRows: []int32{1, 2}, Scrolled: 2},
+		},
+		{
+			name:     "scrolled past the screen",
+			previous: testSnapshot(10, cursor, "a", "b", "c"),
+			current:  testSnapshot(20, cursor, "a", "b", "c"),
+			expected: ScreenDiff{ChangedRows: []int32{0, 1, 2}, Scrolled: 10},
+		},
+	}
+
+	for _, e := range examples {
+		diff := diffScreenSnapshots(e.previous, e.current)
+		e.expected.Snapshot = e.current
+
+		if !reflect.DeepEqual(*diff, e.expected) {
+			t.Errorf("%s: expected %+v, got %+v", e.name, e.expected, *diff)
+		}
+	}
+}
+
+func TestFetchTime(t *testing.T) {
+	opts := ScreenStreamerOptions{Interval: 100 * time.Millisecond, Debounce: 20 * time.Millisecond}
+	start := time.Now()
+
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	examples := []struct {
+		name                                string
+		lastUpdate, firstPending, lastFetch time.Time
+		expected                            time.Time
+	}{
+		{"debounced", at(500), at(500), at(0), at(520)},
+		{"burst debounced", at(550), at(500), at(0), at(570)},
+		{"burst capped by interval", at(590), at(500), at(0), at(600)},
+		{"too soon after the last fetch", at(30), at(30), at(0), at(100)},
+		{"first update", at(0), at(0), time.Time{}, at(20)},
+	}
+
+	for _, e := range examples {
+		if due := fetchTime(opts, e.lastUpdate, e.firstPending, e.lastFetch); !due.Equal(e.expected) {
+			t.Errorf("%s: expected %s, got %s", e.name, e.expected.Sub(start), due.Sub(start))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Command genmenuitems generates the MenuItem constants of itermctl from mainmenu.py, the module of iTerm2's Python API
+// that lists the identifiers of the main menu's items. It reads mainmenu.py from stdin and writes Go code to stdout.
+// Use it with "make update_menu_items".
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	classRe = regexp.MustCompile(`^(\s*)class (\w+)\(enum\.Enum\):`)
+	itemRe  = regexp.MustCompile(`^(\s*)(\w+) = MenuItemIdentifier\(("(?:[^"\\]|\\.)*"), ("(?:[^"\\]|\\.)*")\)`)
+)
+
+type menuItem struct {
+	// path holds the names of the classes enclosing the item, outermost first.
+	path       []string
+	constant   string
+	title      string
+	identifier string
+}
+
+type class struct {
+	indent int
+	name   string
+}
+
+func main() {
+	items, err := parse(os.Stdin)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(items) == 0 {
+		log.Fatal("no menu items found")
+	}
+
+	src, err := generate(items)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := os.Stdout.Write(src); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func parse(r io.Reader) ([]menuItem, error) {
+	var items []menuItem
+	var classes []class
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := classRe.FindStringSubmatch(line); m != nil {
+			classes = enclosing(classes, len(m[1]))
+			classes = append(classes, class{indent: len(m[1]), name: m[2]})
+			continue
+		}
+
+		m := itemRe.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		classes = enclosing(classes, len(m[1]))
+
+		title, err := strconv.Unquote(m[3])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m[2], err)
+		}
+
+		identifier, err := strconv.Unquote(m[4])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", m[2], err)
+		}
+
+		var path []string
+		for _, c := range classes {
+			path = append(path, c.name)
+		}
+
+		items = append(items, menuItem{path: path, constant: m[2], title: title, identifier: identifier})
+	}
+
+	return items, scanner.Err()
+}
+
+// enclosing drops the classes that a line with the given indentation is not part of.
+func enclosing(classes []class, indent int) []class {
+	for len(classes) > 0 && classes[len(classes)-1].indent >= indent {
+		classes = classes[:len(classes)-1]
+	}
+	return classes
+}
+
+func generate(items []menuItem) ([]byte, error) {
+	counts := make(map[string]int)
+	for _, item := range items {
+		counts[camelCase(item.constant)]++
+	}
+
+	names := make(map[string]bool)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("// Code generated by scripts/genmenuitems from iTerm2's mainmenu.py; DO NOT EDIT.\n\n")
+	buf.WriteString("package itermctl\n\n")
+	buf.WriteString("// Identifiers of the items of iTerm2's main menu, for use with App.InvokeMenuItem and App.MenuItemState.\n")
+	buf.WriteString("const (\n")
+
+	for _, item := range items {
+		name := camelCase(item.constant)
+		if counts[name] > 1 && len(item.path) > 0 {
+			name = camelCase(item.path[len(item.path)-1]) + name
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("%s: duplicate constant Menu%s", item.identifier, name)
+		}
+		names[name] = true
+
+		fmt.Fprintf(buf, "\t// Menu%s selects %s\n", name, strings.Join(item.menuTitles(), " > "))
+		fmt.Fprintf(buf, "\tMenu%s MenuItem = %q\n", name, item.identifier)
+	}
+
+	buf.WriteString(")\n")
+
+	return format.Source(buf.Bytes())
+}
+
+// menuTitles returns the titles of the menus leading to the item, followed by the item's title. Submenu items have
+// identifiers made of the submenu's title and their own, such as "Broadcast Input.Send Input to Current Session Only",
+// giving the title of the innermost submenu. Other menu titles are guessed from the class names.
+func (item menuItem) menuTitles() []string {
+	var titles []string
+	for _, name := range item.path {
+		titles = append(titles, splitWords(name))
+	}
+
+	if len(titles) > 1 && strings.HasSuffix(item.identifier, "."+item.title) {
+		titles[len(titles)-1] = strings.TrimSuffix(item.identifier, "."+item.title)
+	}
+
+	return append(titles, item.title)
+}
+
+// splitWords turns a class name, such as BroadcastInput, into words separated by spaces. Capitals following a single
+// leading lowercase letter, as in iTerm2, don't start a new word.
+func splitWords(name string) string {
+	var b strings.Builder
+
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 1 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteRune(' ')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// camelCase turns a Python constant name, such as TOGGLE_FULL_SCREEN, into ToggleFullScreen. Class names, which are
+// already in camel case, only get their first letter capitalized.
+func camelCase(name string) string {
+	if strings.ToUpper(name) != name {
+		return strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	var b strings.Builder
+
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + strings.ToLower(word[1:]))
+	}
+
+	return b.String()
+}
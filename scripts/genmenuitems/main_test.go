@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sample = `import enum
+
+
+class MainMenu:
+    class iTerm2(enum.Enum):
+        ABOUT_ITERM2 = MenuItemIdentifier("About iTerm2", "About iTerm2")
+        QUIT_ITERM2 = MenuItemIdentifier("Quit \"iTerm2\"", "Quit iTerm2")
+
+    class Shell(enum.Enum):
+        CLOSE = MenuItemIdentifier("Close", "Close")
+
+        class BroadcastInput(enum.Enum):
+            SEND_INPUT_TO_CURRENT_SESSION_ONLY = MenuItemIdentifier("Send Input to Current Session Only", "Broadcast Input.Send Input to Current Session Only")
+
+        NEW_TAB = MenuItemIdentifier("New Tab", "New Tab")
+
+    class Window(enum.Enum):
+        CLOSE = MenuItemIdentifier("Close", "Close Window")
+`
+
+func TestParse(t *testing.T) {
+	items, err := parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []menuItem{
+		{path: []string{"iTerm2"}, constant: "ABOUT_ITERM2", title: "About iTerm2", identifier: "About iTerm2"},
+		{path: []string{"iTerm2"}, constant: "QUIT_ITERM2", title: `Quit "iTerm2"`, identifier: "Quit iTerm2"},
+		{path: []string{"Shell"}, constant: "CLOSE", title: "Close", identifier: "Close"},
+		{path: []string{"Shell", "BroadcastInput"}, constant: "SEND_INPUT_TO_CURRENT_SESSION_ONLY",
+			title: "Send Input to Current Session Only", identifier: "Broadcast Input.Send Input to Current Session Only"},
+		{path: []string{"Shell"}, constant: "NEW_TAB", title: "New Tab", identifier: "New Tab"},
+		{path: []string{"Window"}, constant: "CLOSE", title: "Close", identifier: "Close Window"},
+	}
+
+	if !reflect.DeepEqual(items, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, items)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	items, err := parse(strings.NewReader(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"// Code generated by scripts/genmenuitems from iTerm2's mainmenu.py; DO NOT EDIT.\n",
+		"\t// MenuAboutIterm2 selects iTerm2 > About iTerm2\n\tMenuAboutIterm2 MenuItem = \"About iTerm2\"\n",
+		"\t// MenuShellClose selects Shell > Close\n\tMenuShellClose MenuItem = \"Close\"\n",
+		"\t// MenuSendInputToCurrentSessionOnly selects Shell > Broadcast Input > Send Input to Current Session Only\n",
+		"\t// MenuWindowClose selects Window > Close\n\tMenuWindowClose MenuItem = \"Close Window\"\n",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(string(src), e) {
+			t.Errorf("expected the generated code to contain %q, got:\n%s", e, src)
+		}
+	}
+}
+
+func TestGenerate_Collision(t *testing.T) {
+	items := []menuItem{
+		{constant: "CLOSE", title: "Close", identifier: "Close"},
+		{constant: "CLOSE", title: "Close", identifier: "Close All"},
+	}
+
+	if _, err := generate(items); err == nil {
+		t.Fatal("expected an error for colliding top-level items")
+	}
+}
+
+func TestGenerate_Testdata(t *testing.T) {
+	f, err := os.Open("testdata/mainmenu.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	items, err := parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := generate(items); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	examples := map[string]string{
+		"Shell":          "Shell",
+		"BroadcastInput": "Broadcast Input",
+		"iTerm2":         "iTerm2",
+		"tmux":           "tmux",
+	}
+
+	for name, expected := range examples {
+		if words := splitWords(name); words != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, words)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+import enum
+
+
+class MenuItemIdentifier:
+    def __init__(self, title, identifier):
+        self.__title = title
+        self.__identifier = identifier
+
+
+class MainMenu:
+    class iTerm2(enum.Enum):
+        ABOUT_ITERM2 = MenuItemIdentifier("About iTerm2", "About iTerm2")
+        PREFERENCES = MenuItemIdentifier("Preferences...", "Preferences...")
+        HIDE_ITERM2 = MenuItemIdentifier("Hide iTerm2", "Hide iTerm2")
+        HIDE_OTHERS = MenuItemIdentifier("Hide Others", "Hide Others")
+        SHOW_ALL = MenuItemIdentifier("Show All", "Show All")
+        SECURE_KEYBOARD_ENTRY = MenuItemIdentifier("Secure Keyboard Entry", "Secure Keyboard Entry")
+        INSTALL_SHELL_INTEGRATION = MenuItemIdentifier("Install Shell Integration", "Install Shell Integration")
+        QUIT_ITERM2 = MenuItemIdentifier("Quit iTerm2", "Quit iTerm2")
+
+    class Shell(enum.Enum):
+        NEW_WINDOW = MenuItemIdentifier("New Window", "New Window")
+        NEW_TAB = MenuItemIdentifier("New Tab", "New Tab")
+        SPLIT_HORIZONTALLY_WITH_CURRENT_PROFILE = MenuItemIdentifier("Split Horizontally with Current Profile", "Split Horizontally with Current Profile")
+        SPLIT_VERTICALLY_WITH_CURRENT_PROFILE = MenuItemIdentifier("Split Vertically with Current Profile", "Split Vertically with Current Profile")
+        CLOSE_WINDOW = MenuItemIdentifier("Close Window", "Close Window")
+
+        class BroadcastInput(enum.Enum):
+            SEND_INPUT_TO_CURRENT_SESSION_ONLY = MenuItemIdentifier("Send Input to Current Session Only", "Broadcast Input.Send Input to Current Session Only")
+            BROADCAST_INPUT_TO_ALL_PANES_IN_ALL_TABS = MenuItemIdentifier("Broadcast Input to All Panes in All Tabs", "Broadcast Input.Broadcast Input to All Panes in All Tabs")
+            BROADCAST_INPUT_TO_ALL_PANES_IN_CURRENT_TAB = MenuItemIdentifier("Broadcast Input to All Panes in Current Tab", "Broadcast Input.Broadcast Input to All Panes in Current Tab")
+            TOGGLE_BROADCAST_INPUT_TO_CURRENT_SESSION = MenuItemIdentifier("Toggle Broadcast Input to Current Session", "Broadcast Input.Toggle Broadcast Input to Current Session")
+
+    class Edit(enum.Enum):
+        UNDO = MenuItemIdentifier("Undo", "Undo")
+        REDO = MenuItemIdentifier("Redo", "Redo")
+        CUT = MenuItemIdentifier("Cut", "Cut")
+        COPY = MenuItemIdentifier("Copy", "Copy")
+        PASTE = MenuItemIdentifier("Paste", "Paste")
+        SELECT_ALL = MenuItemIdentifier("Select All", "Select All")
+        CLEAR_BUFFER = MenuItemIdentifier("Clear Buffer", "Clear Buffer")
+        CLEAR_SCROLLBACK_BUFFER = MenuItemIdentifier("Clear Scrollback Buffer", "Clear Scrollback Buffer")
+
+    class View(enum.Enum):
+        TOGGLE_FULL_SCREEN = MenuItemIdentifier("Toggle Full Screen", "Toggle Full Screen")
+        USE_TRANSPARENCY = MenuItemIdentifier("Use Transparency", "Use Transparency")
+        SHOW_TIMESTAMPS = MenuItemIdentifier("Show Timestamps", "Show Timestamps")
+
+    class Window(enum.Enum):
+        MINIMIZE = MenuItemIdentifier("Minimize", "Minimize")
+        ZOOM = MenuItemIdentifier("Zoom", "Zoom")
@@ -0,0 +1,301 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mrz.io/itermctl/iterm2"
+	"mrz.io/itermctl/screen"
+)
+
+// DefaultScrollbackPageSize is the number of screen lines fetched at once by a Scrollback, unless specified otherwise.
+const DefaultScrollbackPageSize = 500
+
+// ScrollbackOptions configures a Scrollback.
+type ScrollbackOptions struct {
+	// PageSize is the number of screen lines fetched by each request; zero means DefaultScrollbackPageSize.
+	PageSize int64
+
+	// Reverse iterates from the cursor line up to the oldest line of the history.
+	Reverse bool
+
+	// Follow keeps iterating after the last complete line, waiting for the session to output new ones until the context
+	// is done. The line of the cursor is considered incomplete. Follow is ignored when Reverse is true.
+	Follow bool
+}
+
+// ScrollbackLine is a logical line of a session, made of one or more screen lines joined at soft line breaks.
+type ScrollbackLine struct {
+	// Y is the absolute line number of the first screen line.
+	Y int64
+	// Rows is the number of screen lines.
+	Rows int64
+	Text string
+}
+
+// Scrollback iterates over the logical lines of a session's history and screen, fetching them page by page. Create
+// one with Session.Scrollback, then call Next until it returns false and check Err:
+//
+//	sb := session.Scrollback(ctx, itermctl.ScrollbackOptions{})
+//	for sb.Next() {
+//		fmt.Println(sb.Line().Text)
+//	}
+//	if err := sb.Err(); err != nil {
+//		...
+//	}
+type Scrollback struct {
+	ctx     context.Context
+	session *Session
+	opts    ScrollbackOptions
+	updates chan struct{}
+	started bool
+	next    int64
+	partial *ScrollbackLine
+	queue   []ScrollbackLine
+	line    ScrollbackLine
+	lost    int64
+	done    bool
+	err     error
+}
+
+// Scrollback returns an iterator over the session's lines. Iteration stops with an error when the context is done.
+func (s *Session) Scrollback(ctx context.Context, opts ScrollbackOptions) *Scrollback {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultScrollbackPageSize
+	}
+
+	sb := &Scrollback{ctx: ctx, session: s, opts: opts}
+
+	if opts.Follow && !opts.Reverse {
+		updates, err := MonitorScreenUpdates(ctx, s.conn, s.id)
+		if err != nil {
+			sb.err = fmt.Errorf("scrollback: %w", err)
+			return sb
+		}
+
+		// coalesce screen updates, so that a slow reader doesn't hold up the Connection
+		sb.updates = make(chan struct{}, 1)
+		go func() {
+			for range updates {
+				select {
+				case sb.updates <- struct{}{}:
+				default:
+				}
+			}
+		}()
+	}
+
+	return sb
+}
+
+// Next advances to the next line, which is then available through Line. It returns false when there are no more
+// lines or an error occurred.
+func (sb *Scrollback) Next() bool {
+	for len(sb.queue) == 0 {
+		if sb.err != nil || sb.done {
+			return false
+		}
+
+		if err := sb.ctx.Err(); err != nil {
+			sb.err = err
+			return false
+		}
+
+		var err error
+		if sb.opts.Reverse {
+			err = sb.fetchReverse()
+		} else {
+			err = sb.fetchForward()
+		}
+
+		if err != nil {
+			sb.err = fmt.Errorf("scrollback: %w", err)
+		}
+	}
+
+	sb.line, sb.queue = sb.queue[0], sb.queue[1:]
+	return true
+}
+
+// Line returns the current line.
+func (sb *Scrollback) Line() ScrollbackLine {
+	return sb.line
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (sb *Scrollback) Err() error {
+	return sb.err
+}
+
+// Lost returns the number of screen lines that were dropped from the head of the history before they could be read.
+func (sb *Scrollback) Lost() int64 {
+	return sb.lost
+}
+
+func (sb *Scrollback) fetchForward() error {
+	read, caughtUp, err := sb.readForward()
+	if err != nil || !caughtUp {
+		return err
+	}
+
+	if !sb.opts.Follow {
+		sb.flush()
+		sb.done = true
+		return nil
+	}
+
+	if read == 0 {
+		select {
+		case <-sb.updates:
+		case <-sb.ctx.Done():
+		}
+	}
+
+	return nil
+}
+
+// readForward reads the next page of lines, returning how many were read and whether the end was reached. The page is
+// read in a transaction, so that no line scrolls into the history between finding the first line and reading it.
+func (sb *Scrollback) readForward() (int64, bool, error) {
+	tx, err := sb.session.conn.Transaction()
+	if err != nil {
+		return 0, false, err
+	}
+
+	defer sb.endTransaction(tx)
+
+	nl, err := sb.session.NumberOfLines()
+	if err != nil {
+		return 0, false, err
+	}
+
+	first := int64(nl.Overflow)
+
+	if !sb.started {
+		sb.next = first
+		sb.started = true
+	}
+
+	if sb.next < first {
+		sb.lost += first - sb.next
+		sb.next = first
+		sb.partial = nil
+	}
+
+	sc, err := sb.session.ScreenContents(screen.LineRange(sb.next, sb.next+sb.opts.PageSize).Windowed().Proto())
+	if err != nil {
+		return 0, false, err
+	}
+
+	end := sc.GetCursor().GetY()
+	if !sb.opts.Follow {
+		end++
+	}
+
+	read := int64(0)
+
+	for _, line := range sc.GetContents() {
+		if sb.next >= end {
+			break
+		}
+
+		sb.appendForward(sb.next, line)
+		sb.next++
+		read++
+	}
+
+	return read, sb.next >= end, nil
+}
+
+func (sb *Scrollback) appendForward(y int64, line *iterm2.LineContents) {
+	if sb.partial == nil {
+		sb.partial = &ScrollbackLine{Y: y}
+	}
+
+	sb.partial.Text += line.GetText()
+	sb.partial.Rows++
+
+	if line.GetContinuation() != iterm2.LineContents_CONTINUATION_SOFT_EOL {
+		sb.flush()
+	}
+}
+
+// fetchReverse reads the previous page of lines, in a transaction like readForward.
+func (sb *Scrollback) fetchReverse() error {
+	tx, err := sb.session.conn.Transaction()
+	if err != nil {
+		return err
+	}
+
+	defer sb.endTransaction(tx)
+
+	nl, err := sb.session.NumberOfLines()
+	if err != nil {
+		return err
+	}
+
+	first := int64(nl.Overflow)
+
+	if !sb.started {
+		lines, err := sb.session.TrailingLines(1)
+		if err != nil {
+			return err
+		}
+
+		sb.next = lines.GetCursor().GetY() + 1
+		sb.started = true
+	}
+
+	if sb.next <= first {
+		sb.flush()
+		sb.done = true
+		return nil
+	}
+
+	start := sb.next - sb.opts.PageSize
+	if start < first {
+		start = first
+	}
+
+	sc, err := sb.session.ScreenContents(screen.LineRange(start, sb.next).Windowed().Proto())
+	if err != nil {
+		return err
+	}
+
+	lines := sc.GetContents()
+	if int64(len(lines)) > sb.next-start {
+		lines = lines[:sb.next-start]
+	}
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+
+		if sb.partial != nil && line.GetContinuation() != iterm2.LineContents_CONTINUATION_SOFT_EOL {
+			sb.flush()
+		}
+
+		if sb.partial == nil {
+			sb.partial = &ScrollbackLine{}
+		}
+
+		sb.partial.Y = start + int64(i)
+		sb.partial.Text = line.GetText() + sb.partial.Text
+		sb.partial.Rows++
+	}
+
+	sb.next = start
+	return nil
+}
+
+func (sb *Scrollback) endTransaction(tx *Transaction) {
+	if err := tx.End(); err != nil {
+		logrus.Errorf("scrollback: %s", err)
+	}
+}
+
+func (sb *Scrollback) flush() {
+	if sb.partial != nil {
+		sb.queue = append(sb.queue, *sb.partial)
+		sb.partial = nil
+	}
+}
@@ -0,0 +1,202 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mrz.io/itermctl/iterm2"
+	"mrz.io/itermctl/screen"
+	"strings"
+)
+
+var (
+	ErrSelectionInvalidSession   = fmt.Errorf("SelectionResponse_INVALID_SESSION")
+	ErrSelectionInvalidRange     = fmt.Errorf("SelectionResponse_INVALID_RANGE")
+	ErrSelectionRequestMalformed = fmt.Errorf("SelectionResponse_REQUEST_MALFORMED")
+)
+
+// SelectionMode is the way a SubSelection was made, which determines how its range is extended and read.
+type SelectionMode int32
+
+const (
+	SelectionCharacter = SelectionMode(iterm2.SelectionMode_CHARACTER)
+	SelectionWord      = SelectionMode(iterm2.SelectionMode_WORD)
+	SelectionLine      = SelectionMode(iterm2.SelectionMode_LINE)
+	SelectionSmart     = SelectionMode(iterm2.SelectionMode_SMART)
+	SelectionBox       = SelectionMode(iterm2.SelectionMode_BOX)
+	SelectionWholeLine = SelectionMode(iterm2.SelectionMode_WHOLE_LINE)
+)
+
+// Coord is a cell position, see screen.Coord.
+type Coord = screen.Coord
+
+// CoordRange is a range of cells, see screen.CoordRange.
+type CoordRange = screen.CoordRange
+
+// ColumnRange is a window of columns, see screen.ColumnRange.
+type ColumnRange = screen.ColumnRange
+
+// SubSelection is a contiguous part of a Selection.
+type SubSelection struct {
+	Mode  SelectionMode
+	Range CoordRange
+	// Columns restricts each line of Range to a window of columns, like a box selection; nil means all the columns.
+	Columns *ColumnRange
+	// Connected is true if the SubSelection continues with the next one, without a line break in between.
+	Connected bool
+}
+
+// Selection is the text selected in a session, made of one or more SubSelections.
+// See https://iterm2.com/python-api/selection.html.
+type Selection struct {
+	SubSelections []SubSelection
+}
+
+// Selection returns the session's current selection, with no SubSelection if nothing is selected.
+func (s *Session) Selection() (*Selection, error) {
+	resp, err := sendSelectionRequest(s.conn, &iterm2.SelectionRequest{
+		Request: &iterm2.SelectionRequest_GetSelectionRequest_{
+			GetSelectionRequest: &iterm2.SelectionRequest_GetSelectionRequest{SessionId: &s.id},
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("selection: %w", err)
+	}
+
+	sel := &Selection{}
+
+	for _, sub := range resp.GetGetSelectionResponse().GetSelection().GetSubSelections() {
+		sel.SubSelections = append(sel.SubSelections, newSubSelection(sub))
+	}
+
+	return sel, nil
+}
+
+// SetSelection replaces the session's selection; an empty Selection clears it.
+func (s *Session) SetSelection(sel *Selection) error {
+	selection := &iterm2.Selection{}
+
+	for _, sub := range sel.SubSelections {
+		mode := iterm2.SelectionMode(sub.Mode)
+		connected := sub.Connected
+
+		selection.SubSelections = append(selection.SubSelections, &iterm2.SubSelection{
+			WindowedCoordRange: sub.windowedCoordRange(),
+			SelectionMode:      &mode,
+			Connected:          &connected,
+		})
+	}
+
+	_, err := sendSelectionRequest(s.conn, &iterm2.SelectionRequest{
+		Request: &iterm2.SelectionRequest_SetSelectionRequest_{
+			SetSelectionRequest: &iterm2.SelectionRequest_SetSelectionRequest{
+				SessionId: &s.id,
+				Selection: selection,
+			},
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("set selection: %w", err)
+	}
+
+	return nil
+}
+
+// SelectedText returns the text of the session's current selection, see SelectionText.
+func (s *Session) SelectedText() (string, error) {
+	sel, err := s.Selection()
+	if err != nil {
+		return "", fmt.Errorf("selected text: %w", err)
+	}
+
+	return s.SelectionText(sel)
+}
+
+// SelectionText returns the text of a Selection of the session, like `iterm2.selection.Selection.async_get_string`.
+// SubSelections are separated by a line break unless they're connected, and each line of a box SubSelection ends with
+// a line break except the last one.
+func (s *Session) SelectionText(sel *Selection) (string, error) {
+	tx, err := s.conn.Transaction()
+	if err != nil {
+		return "", fmt.Errorf("selection text: %w", err)
+	}
+
+	defer func() {
+		if err := tx.End(); err != nil {
+			logrus.Errorf("selection text: %s", err)
+		}
+	}()
+
+	text := &strings.Builder{}
+
+	for i, sub := range sel.SubSelections {
+		sc, err := s.ScreenContents(sub.windowedCoordRange())
+		if err != nil {
+			return "", fmt.Errorf("selection text: %w", err)
+		}
+
+		box := sub.Mode == SelectionBox || sub.Columns != nil
+		lines := sc.GetContents()
+
+		for j, line := range lines {
+			text.WriteString(line.GetText())
+
+			if j == len(lines)-1 {
+				break
+			}
+
+			if box || line.GetContinuation() == iterm2.LineContents_CONTINUATION_HARD_EOL {
+				text.WriteString("\n")
+			}
+		}
+
+		if i < len(sel.SubSelections)-1 && !sub.Connected {
+			text.WriteString("\n")
+		}
+	}
+
+	return text.String(), nil
+}
+
+func newSubSelection(sub *iterm2.SubSelection) SubSelection {
+	windowedRange := screen.NewWindowedCoordRange(sub.GetWindowedCoordRange())
+
+	return SubSelection{
+		Mode:      SelectionMode(sub.GetSelectionMode()),
+		Range:     windowedRange.CoordRange,
+		Columns:   windowedRange.Columns,
+		Connected: sub.GetConnected(),
+	}
+}
+
+func (sub SubSelection) windowedCoordRange() *iterm2.WindowedCoordRange {
+	return screen.WindowedCoordRange{CoordRange: sub.Range, Columns: sub.Columns}.Proto()
+}
+
+func sendSelectionRequest(conn *Connection, selReq *iterm2.SelectionRequest) (*iterm2.SelectionResponse, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_SelectionRequest{
+			SelectionRequest: selReq,
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.GetSelectionResponse().GetStatus() {
+	case iterm2.SelectionResponse_OK:
+		return resp.GetSelectionResponse(), nil
+	case iterm2.SelectionResponse_INVALID_SESSION:
+		return nil, ErrSelectionInvalidSession
+	case iterm2.SelectionResponse_INVALID_RANGE:
+		return nil, ErrSelectionInvalidRange
+	case iterm2.SelectionResponse_REQUEST_MALFORMED:
+		return nil, ErrSelectionRequestMalformed
+	default:
+		return nil, fmt.Errorf("%s", resp.GetSelectionResponse().GetStatus())
+	}
+}
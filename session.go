@@ -3,13 +3,16 @@ package itermctl
 import (
 	"context"
 	"fmt"
-	"github.com/sirupsen/logrus"
+	"io"
 	"mrz.io/itermctl/internal/json"
 	"mrz.io/itermctl/iterm2"
+	"mrz.io/itermctl/screen"
 	"strings"
 	"sync"
 )
 
+var ErrSessionNotRestartable = fmt.Errorf("RestartSessionResponse_SESSION_NOT_RESTARTABLE")
+
 type NumberOfLines struct {
 	FirstVisible int32 `json:"first_visible"`
 	Overflow     int32 `json:"overflow"`
@@ -44,6 +47,11 @@ func (s *Session) Id() string {
 	return s.id
 }
 
+// Conn returns the Connection the session is controlled through.
+func (s *Session) Conn() *Connection {
+	return s.conn
+}
+
 func (s *Session) Active() bool {
 	s.mx.Lock()
 	defer s.mx.Unlock()
@@ -65,7 +73,18 @@ func (s *Session) Activate() error {
 
 // SplitPane splits the pane of the this session, returning the new session IDs on success.
 func (s *Session) SplitPane(vertical bool, before bool) ([]string, error) {
-	// TODO profile and profile_customizations flags
+	return s.SplitPaneWithProfile(vertical, before, "")
+}
+
+// SplitPaneWithProfile splits the pane of this session like SplitPane, starting the new session with the named profile
+// (or the default profile if profileName is empty) modified by the given customizations.
+func (s *Session) SplitPaneWithProfile(vertical bool, before bool, profileName string,
+	customizations ...Assignment) ([]string, error) {
+
+	properties, err := profileProperties(customizations)
+	if err != nil {
+		return nil, fmt.Errorf("split pane: %w", err)
+	}
 
 	var direction iterm2.SplitPaneRequest_SplitDirection
 	if vertical {
@@ -77,13 +96,18 @@ func (s *Session) SplitPane(vertical bool, before bool) ([]string, error) {
 	req := &iterm2.ClientOriginatedMessage{
 		Submessage: &iterm2.ClientOriginatedMessage_SplitPaneRequest{
 			SplitPaneRequest: &iterm2.SplitPaneRequest{
-				Session:        &s.id,
-				SplitDirection: &direction,
-				Before:         &before,
+				Session:                 &s.id,
+				SplitDirection:          &direction,
+				Before:                  &before,
+				CustomProfileProperties: properties,
 			},
 		},
 	}
 
+	if profileName != "" {
+		req.GetSplitPaneRequest().ProfileName = &profileName
+	}
+
 	resp, err := s.conn.GetResponse(context.Background(), req)
 	if err != nil {
 		return nil, fmt.Errorf("split pane: %w", err)
@@ -109,9 +133,40 @@ func (s *Session) Close(force bool) error {
 	})
 }
 
-// SendText sends text to the session, optionally broadcasting it if broadcast is enabled.
-func (s *Session) SendText(text string, useBroadcastIfEnabled bool) error {
-	suppressBroadcast := useBroadcastIfEnabled
+// Restart restarts the session's job. If onlyIfExited is true and the job is still running, ErrSessionNotRestartable
+// is returned; some sessions, such as tmux integration sessions, can't be restarted at all.
+func (s *Session) Restart(onlyIfExited bool) error {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_RestartSessionRequest{
+			RestartSessionRequest: &iterm2.RestartSessionRequest{
+				SessionId:    &s.id,
+				OnlyIfExited: &onlyIfExited,
+			},
+		},
+	}
+
+	resp, err := s.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("restart session: %w", err)
+	}
+
+	switch resp.GetRestartSessionResponse().GetStatus() {
+	case iterm2.RestartSessionResponse_OK:
+		return nil
+	case iterm2.RestartSessionResponse_SESSION_NOT_FOUND:
+		return fmt.Errorf("restart session: %w", ErrSessionNotFound)
+	case iterm2.RestartSessionResponse_SESSION_NOT_RESTARTABLE:
+		return fmt.Errorf("restart session: %w", ErrSessionNotRestartable)
+	default:
+		return fmt.Errorf("restart session: %s", resp.GetRestartSessionResponse().GetStatus())
+	}
+}
+
+// SendText sends text to the session. If broadcast is true and the session is part of a broadcast domain, the text is
+// also sent to the other sessions of the domain; otherwise it's sent to this session only, even when broadcasting
+// input is enabled.
+func (s *Session) SendText(text string, broadcast bool) error {
+	suppressBroadcast := !broadcast
 
 	req := &iterm2.ClientOriginatedMessage{
 		Submessage: &iterm2.ClientOriginatedMessage_SendTextRequest{
@@ -135,6 +190,53 @@ func (s *Session) SendText(text string, useBroadcastIfEnabled bool) error {
 	return nil
 }
 
+// Inject writes data to the session's screen as if the session's job had printed it, without sending any keystroke to
+// the job. Control and escape sequences in data are interpreted like any other output.
+func (s *Session) Inject(data []byte) error {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_InjectRequest{
+			InjectRequest: &iterm2.InjectRequest{
+				SessionId: []string{s.id},
+				Data:      data,
+			},
+		},
+	}
+
+	resp, err := s.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return fmt.Errorf("inject: %w", err)
+	}
+
+	for _, status := range resp.GetInjectResponse().GetStatus() {
+		switch status {
+		case iterm2.InjectResponse_OK:
+		case iterm2.InjectResponse_SESSION_NOT_FOUND:
+			return fmt.Errorf("inject: %w", ErrSessionNotFound)
+		default:
+			return fmt.Errorf("inject: %s", status)
+		}
+	}
+
+	return nil
+}
+
+// OutputWriter returns an io.Writer that injects everything written to it in the session, see Inject.
+func (s *Session) OutputWriter() io.Writer {
+	return &sessionOutputWriter{session: s}
+}
+
+type sessionOutputWriter struct {
+	session *Session
+}
+
+func (w *sessionOutputWriter) Write(p []byte) (int, error) {
+	if err := w.session.Inject(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
 func (s *Session) TrailingLines(n int32) (*iterm2.GetBufferResponse, error) {
 	req := &iterm2.ClientOriginatedMessage{
 		Submessage: &iterm2.ClientOriginatedMessage_GetBufferRequest{
@@ -194,6 +296,17 @@ func (s *Session) NumberOfLines() (NumberOfLines, error) {
 	return result, nil
 }
 
+// Viewport returns the position of the session's screen in its scrollback, to convert absolute line numbers to rows of
+// the screen.
+func (s *Session) Viewport() (screen.Viewport, error) {
+	nl, err := s.NumberOfLines()
+	if err != nil {
+		return screen.Viewport{}, fmt.Errorf("viewport: %w", err)
+	}
+
+	return screen.Viewport{FirstLine: int64(nl.Overflow) + int64(nl.History), Height: nl.Grid}, nil
+}
+
 func (s *Session) Buried() (bool, error) {
 	var result bool
 	if err := s.getSessionProperty("buried", &result); err != nil {
@@ -203,48 +316,6 @@ func (s *Session) Buried() (bool, error) {
 	return result, nil
 }
 
-// SelectedText returns the first subselection as a string.
-// TODO merge all subselections as in `iterm2.selection.Selection.async_get_string`
-func (s *Session) SelectedText() (string, error) {
-	req := &iterm2.ClientOriginatedMessage{
-		Submessage: &iterm2.ClientOriginatedMessage_SelectionRequest{
-			SelectionRequest: &iterm2.SelectionRequest{
-				Request: &iterm2.SelectionRequest_GetSelectionRequest_{
-					GetSelectionRequest: &iterm2.SelectionRequest_GetSelectionRequest{SessionId: &s.id},
-				},
-			},
-		},
-	}
-
-	resp, err := s.conn.GetResponse(context.Background(), req)
-
-	if err != nil {
-		return "", fmt.Errorf("selected text: %w", err)
-	}
-
-	tx, err := s.conn.Transaction()
-	if err != nil {
-		return "", fmt.Errorf("selected text: %w", err)
-	}
-
-	defer func() {
-		if err := tx.End(); err != nil {
-			logrus.Errorf("selected text: %s", err)
-		}
-	}()
-
-	for _, subsel := range resp.GetSelectionResponse().GetGetSelectionResponse().GetSelection().GetSubSelections() {
-		sc, err := s.ScreenContents(subsel.GetWindowedCoordRange())
-		if err != nil {
-			return "", fmt.Errorf("selected text: %w", err)
-		}
-
-		return ToString(sc.GetContents()), nil
-	}
-
-	return "", nil
-}
-
 func (s *Session) getSessionProperty(propName string, target interface{}) error {
 	req := &iterm2.ClientOriginatedMessage{
 		Submessage: &iterm2.ClientOriginatedMessage_GetPropertyRequest{
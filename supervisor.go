@@ -0,0 +1,385 @@
+package itermctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"mrz.io/itermctl/iterm2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RestartPolicy decides which exits a Supervisor restarts.
+type RestartPolicy int
+
+const (
+	// RestartAlways restarts sessions whatever their exit status.
+	RestartAlways RestartPolicy = iota
+	// RestartOnFailure restarts sessions only when they exit with a non-zero or unknown status.
+	RestartOnFailure
+)
+
+// ExitStatusUnknown is the exit status recorded when the Supervisor can't tell how a session exited.
+const ExitStatusUnknown int32 = -1
+
+const (
+	DefaultInitialBackoff = 1 * time.Second
+	DefaultMaxBackoff     = 1 * time.Minute
+	DefaultPollInterval   = 2 * time.Second
+)
+
+// SupervisorOptions configures a Supervisor. Zero durations are replaced by their defaults.
+type SupervisorOptions struct {
+	Policy RestartPolicy
+
+	// InitialBackoff is the delay before restarting a session after its first failure; the delay doubles after each
+	// consecutive failure, up to MaxBackoff. A run lasting longer than MaxBackoff resets the count of failures.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// MaxRetries is how many times in a row a failing session is restarted before the Supervisor gives up on it, which is
+	// recorded with a RestartRecord that isn't Restarted. Zero means no limit.
+	MaxRetries int
+
+	// AlertAfter shows an alert when a session fails that many times in a row. Zero disables the alert.
+	AlertAfter int
+
+	// PollInterval is how often sessions watched without a command are checked for exit.
+	PollInterval time.Duration
+}
+
+// RestartRecord records an exit of a supervised session and what the Supervisor did about it.
+type RestartRecord struct {
+	SessionId string
+	Time      time.Time
+	// ExitStatus is the exit status of the supervised command, or ExitStatusUnknown.
+	ExitStatus int32
+	// Restarted is false if the session was left alone because of the policy or MaxRetries, or if it couldn't be
+	// restarted.
+	Restarted bool
+	// Err is the error that prevented restarting the session, if any.
+	Err error
+}
+
+// Supervisor restarts sessions when they exit. Create one with Supervise.
+//
+// Sessions watched with a command are supervised through shell integration: when the command ends, it is sent again
+// to the session's shell. Sessions watched without a command are polled, and their job is restarted with
+// Session.Restart once it has exited. Terminated sessions are no longer supervised.
+type Supervisor struct {
+	app     *App
+	opts    SupervisorOptions
+	mx      *sync.Mutex
+	watched map[string]*supervised
+	history map[string][]RestartRecord
+	due     chan string
+}
+
+type supervised struct {
+	command    string
+	running    bool
+	started    time.Time
+	failures   int
+	pending    bool
+	exitStatus int32
+	nextPoll   time.Time
+	gaveUp     bool
+}
+
+// Supervise starts a Supervisor, which watches sessions until the given context is done or the Connection is closed.
+func Supervise(ctx context.Context, app *App, opts SupervisorOptions) (*Supervisor, error) {
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = DefaultInitialBackoff
+	}
+
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+
+	prompts, err := MonitorPrompts(ctx, app.conn, AllSessions,
+		iterm2.PromptMonitorMode_COMMAND_START, iterm2.PromptMonitorMode_COMMAND_END)
+	if err != nil {
+		return nil, fmt.Errorf("supervise: %w", err)
+	}
+
+	terminations, err := MonitorSessionsTermination(ctx, app.conn)
+	if err != nil {
+		return nil, fmt.Errorf("supervise: %w", err)
+	}
+
+	s := &Supervisor{
+		app:     app,
+		opts:    opts,
+		mx:      &sync.Mutex{},
+		watched: make(map[string]*supervised),
+		history: make(map[string][]RestartRecord),
+		due:     make(chan string),
+	}
+
+	go s.run(ctx, prompts, terminations)
+
+	return s, nil
+}
+
+// Watch starts supervising a session. If command is given, the session's shell must have shell integration installed,
+// and the command is expected to be already running or about to be sent by the caller.
+func (s *Supervisor) Watch(sessionId string, command string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.watched[sessionId] = &supervised{command: strings.TrimSpace(command), started: time.Now()}
+}
+
+// Unwatch stops supervising a session.
+func (s *Supervisor) Unwatch(sessionId string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.watched, sessionId)
+}
+
+// History returns the exits recorded for a session, oldest first.
+func (s *Supervisor) History(sessionId string) []RestartRecord {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return append([]RestartRecord(nil), s.history[sessionId]...)
+}
+
+func (s *Supervisor) run(ctx context.Context, prompts <-chan *iterm2.PromptNotification,
+	terminations <-chan *iterm2.TerminateSessionNotification) {
+
+	ticker := time.NewTicker(s.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-prompts:
+			if !ok {
+				return
+			}
+			s.handlePrompt(ctx, n)
+		case n, ok := <-terminations:
+			if !ok {
+				return
+			}
+			s.handleTermination(n.GetSessionId())
+		case sessionId := <-s.due:
+			s.restartCommand(sessionId)
+		case <-ticker.C:
+			s.poll()
+		}
+	}
+}
+
+func (s *Supervisor) handlePrompt(ctx context.Context, n *iterm2.PromptNotification) {
+	s.mx.Lock()
+	w, ok := s.watched[n.GetSession()]
+	if !ok || w.command == "" {
+		s.mx.Unlock()
+		return
+	}
+
+	if n.GetCommandStart() != nil && strings.TrimSpace(n.GetCommandStart().GetCommand()) == w.command {
+		w.running = true
+		w.started = time.Now()
+	}
+
+	if n.GetCommandEnd() == nil || w.pending {
+		s.mx.Unlock()
+		return
+	}
+
+	if w.running {
+		w.running = false
+		s.exited(ctx, n.GetSession(), w, n.GetCommandEnd().GetStatus())
+		s.mx.Unlock()
+		return
+	}
+	s.mx.Unlock()
+
+	// the command may have been running before the session was watched, in which case its start wasn't seen
+	prompt, err := newSession(n.GetSession(), s.app, s.app.conn, false).getPrompt(ctx, n.GetUniquePromptId())
+	if err != nil {
+		log.Warnf("supervisor: session %s: %s", n.GetSession(), err)
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if w, ok := s.watched[n.GetSession()]; ok && !w.running && !w.pending &&
+		strings.TrimSpace(prompt.Command) == w.command {
+		s.exited(ctx, n.GetSession(), w, n.GetCommandEnd().GetStatus())
+	}
+}
+
+func (s *Supervisor) handleTermination(sessionId string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if _, ok := s.watched[sessionId]; !ok {
+		return
+	}
+
+	delete(s.watched, sessionId)
+	s.record(RestartRecord{
+		SessionId:  sessionId,
+		ExitStatus: ExitStatusUnknown,
+		Err:        fmt.Errorf("session terminated: %w", ErrSessionNotFound),
+	})
+}
+
+// exited applies the policy to a command that ended, scheduling its restart after the current backoff delay.
+func (s *Supervisor) exited(ctx context.Context, sessionId string, w *supervised, status int32) {
+	if time.Since(w.started) > s.opts.MaxBackoff {
+		w.failures = 0
+	}
+
+	if status == 0 {
+		w.failures = 0
+		if s.opts.Policy == RestartOnFailure {
+			s.record(RestartRecord{SessionId: sessionId, ExitStatus: status})
+			return
+		}
+	} else {
+		w.failures++
+		s.alertIfFailing(sessionId, w)
+	}
+
+	if s.opts.MaxRetries > 0 && w.failures > s.opts.MaxRetries {
+		w.gaveUp = true
+		s.record(RestartRecord{SessionId: sessionId, ExitStatus: status})
+		return
+	}
+
+	w.pending = true
+	w.exitStatus = status
+
+	time.AfterFunc(s.backoff(w.failures), func() {
+		select {
+		case s.due <- sessionId:
+		case <-ctx.Done():
+		}
+	})
+}
+
+func (s *Supervisor) restartCommand(sessionId string) {
+	s.mx.Lock()
+	w, ok := s.watched[sessionId]
+	if !ok || !w.pending {
+		s.mx.Unlock()
+		return
+	}
+	w.pending = false
+	command := w.command
+	status := w.exitStatus
+	s.mx.Unlock()
+
+	// the App may not track the session, eg. if it was created by another client
+	err := newSession(sessionId, s.app, s.app.conn, false).SendText(command+"\n", false)
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.record(RestartRecord{SessionId: sessionId, ExitStatus: status, Restarted: err == nil, Err: err})
+}
+
+// poll restarts the exited sessions watched without a command. Their exit status is unknown, so every exit counts as
+// a failure.
+func (s *Supervisor) poll() {
+	s.mx.Lock()
+	var sessionIds []string
+	for id, w := range s.watched {
+		if w.command == "" && !w.gaveUp && !time.Now().Before(w.nextPoll) {
+			sessionIds = append(sessionIds, id)
+		}
+	}
+	s.mx.Unlock()
+
+	for _, id := range sessionIds {
+		err := newSession(id, s.app, s.app.conn, false).Restart(true)
+		if errors.Is(err, ErrSessionNotRestartable) {
+			continue
+		}
+
+		s.mx.Lock()
+		w, ok := s.watched[id]
+		if !ok {
+			s.mx.Unlock()
+			continue
+		}
+
+		if err != nil {
+			log.Warnf("supervisor: %s", err)
+			s.record(RestartRecord{SessionId: id, ExitStatus: ExitStatusUnknown, Err: err})
+			s.mx.Unlock()
+			continue
+		}
+
+		s.polled(id, w)
+		s.mx.Unlock()
+	}
+}
+
+// polled records the restart of a session watched without a command. Its next exit can't be seen without restarting
+// it again, so the Supervisor gives up on it as soon as it was restarted MaxRetries times in a row.
+func (s *Supervisor) polled(sessionId string, w *supervised) {
+	if time.Since(w.started) > s.opts.MaxBackoff {
+		w.failures = 0
+	}
+
+	w.failures++
+	w.started = time.Now()
+	w.nextPoll = w.started.Add(s.backoff(w.failures))
+	s.record(RestartRecord{SessionId: sessionId, ExitStatus: ExitStatusUnknown, Restarted: true})
+	s.alertIfFailing(sessionId, w)
+
+	if s.opts.MaxRetries > 0 && w.failures >= s.opts.MaxRetries {
+		w.gaveUp = true
+		s.record(RestartRecord{SessionId: sessionId, ExitStatus: ExitStatusUnknown})
+	}
+}
+
+func (s *Supervisor) backoff(failures int) time.Duration {
+	if failures == 0 {
+		return 0
+	}
+
+	delay := s.opts.InitialBackoff
+	for i := 1; i < failures && delay < s.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > s.opts.MaxBackoff {
+		delay = s.opts.MaxBackoff
+	}
+
+	return delay
+}
+
+func (s *Supervisor) alertIfFailing(sessionId string, w *supervised) {
+	if s.opts.AlertAfter == 0 || w.failures != s.opts.AlertAfter {
+		return
+	}
+
+	alert := Alert{
+		Title:    "Supervised session failing",
+		Subtitle: fmt.Sprintf("Session %s failed %d times in a row.", sessionId, w.failures),
+	}
+
+	go func() {
+		if _, err := s.app.ShowAlert(alert, ""); err != nil {
+			log.Warnf("supervisor: %s", err)
+		}
+	}()
+}
+
+func (s *Supervisor) record(r RestartRecord) {
+	r.Time = time.Now()
+	s.history[r.SessionId] = append(s.history[r.SessionId], r)
+}
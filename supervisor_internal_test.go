@@ -0,0 +1,128 @@
+package itermctl
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testSupervisor(opts SupervisorOptions) *Supervisor {
+	return &Supervisor{
+		opts:    opts,
+		mx:      &sync.Mutex{},
+		watched: make(map[string]*supervised),
+		history: make(map[string][]RestartRecord),
+		due:     make(chan string),
+	}
+}
+
+func TestSupervisor_Backoff(t *testing.T) {
+	s := testSupervisor(SupervisorOptions{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	expected := []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for failures, delay := range expected {
+		if d := s.backoff(failures); d != delay {
+			t.Errorf("%d failures: expected %s, got %s", failures, delay, d)
+		}
+	}
+}
+
+func TestSupervisor_Exited(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := testSupervisor(SupervisorOptions{InitialBackoff: time.Millisecond, MaxBackoff: time.Minute, MaxRetries: 2})
+	w := &supervised{command: "true", started: time.Now()}
+
+	for _, status := range []int32{0, 1, 1} {
+		s.exited(ctx, "s1", w, status)
+
+		if !w.pending || w.exitStatus != status {
+			t.Fatalf("expected a restart of status %d to be pending, got %+v", status, w)
+		}
+
+		select {
+		case id := <-s.due:
+			if id != "s1" {
+				t.Fatalf("expected s1 to be due, got %s", id)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the restart")
+		}
+
+		w.pending = false
+	}
+
+	if w.failures != 2 {
+		t.Fatalf("expected 2 failures, got %d", w.failures)
+	}
+
+	s.exited(ctx, "s1", w, 1)
+
+	expected := []RestartRecord{{SessionId: "s1", ExitStatus: 1}}
+	if !w.gaveUp || w.pending || !reflect.DeepEqual(withoutTime(s.History("s1")), expected) {
+		t.Fatalf("expected to give up after 3 failures, got %+v and %+v", w, s.History("s1"))
+	}
+
+	// a long enough run resets the count of failures
+	w = &supervised{command: "true", started: time.Now().Add(-2 * time.Minute), failures: 2}
+	s.exited(ctx, "s2", w, 1)
+
+	if w.failures != 1 || !w.pending {
+		t.Fatalf("expected the failures to be reset, got %+v", w)
+	}
+}
+
+func TestSupervisor_Exited_RestartOnFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s := testSupervisor(SupervisorOptions{Policy: RestartOnFailure, InitialBackoff: time.Millisecond, MaxBackoff: time.Minute})
+	w := &supervised{command: "true", started: time.Now(), failures: 1}
+
+	s.exited(ctx, "s1", w, 0)
+
+	expected := []RestartRecord{{SessionId: "s1", ExitStatus: 0}}
+	if w.pending || w.failures != 0 || !reflect.DeepEqual(withoutTime(s.History("s1")), expected) {
+		t.Fatalf("expected a successful exit not to be restarted, got %+v and %+v", w, s.History("s1"))
+	}
+
+	s.exited(ctx, "s1", w, 2)
+
+	if !w.pending || w.failures != 1 {
+		t.Fatalf("expected a failure to be restarted, got %+v", w)
+	}
+}
+
+func TestSupervisor_Polled(t *testing.T) {
+	s := testSupervisor(SupervisorOptions{InitialBackoff: time.Second, MaxBackoff: time.Minute, MaxRetries: 2})
+	w := &supervised{started: time.Now()}
+
+	s.polled("s1", w)
+
+	if w.gaveUp || w.failures != 1 || w.nextPoll.Sub(w.started) != time.Second {
+		t.Fatalf("expected a first restart, got %+v", w)
+	}
+
+	s.polled("s1", w)
+
+	expected := []RestartRecord{
+		{SessionId: "s1", ExitStatus: ExitStatusUnknown, Restarted: true},
+		{SessionId: "s1", ExitStatus: ExitStatusUnknown, Restarted: true},
+		{SessionId: "s1", ExitStatus: ExitStatusUnknown},
+	}
+
+	if !w.gaveUp || w.nextPoll.Sub(w.started) != 2*time.Second ||
+		!reflect.DeepEqual(withoutTime(s.History("s1")), expected) {
+		t.Fatalf("expected to give up after 2 restarts, got %+v and %+v", w, s.History("s1"))
+	}
+}
+
+func withoutTime(history []RestartRecord) []RestartRecord {
+	for i := range history {
+		history[i].Time = time.Time{}
+	}
+	return history
+}
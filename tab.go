@@ -0,0 +1,450 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"mrz.io/itermctl/iterm2"
+	"sort"
+)
+
+var (
+	ErrTabNotFound        = fmt.Errorf("tab not found")
+	ErrPaneNotFound       = fmt.Errorf("pane not found")
+	ErrNoAdjacentPane     = fmt.Errorf("no adjacent pane in that direction")
+	ErrInvalidPaneSize    = fmt.Errorf("pane size out of range")
+	ErrWrongSplitTree     = fmt.Errorf("split tree does not match the tab's panes")
+	ErrIncompatibleLayout = fmt.Errorf("layout incompatible with the tab's panes")
+)
+
+// ResizeDirection is the direction towards which ResizePane grows a pane.
+type ResizeDirection int
+
+const (
+	ResizeLeft ResizeDirection = iota
+	ResizeRight
+	ResizeUp
+	ResizeDown
+)
+
+// LayoutPreset computes the pane sizes of a tab, as tmux's layouts do. Unlike tmux, presets can't move panes around:
+// iTerm2 only allows resizing them, so a preset may be incompatible with how a tab is split.
+type LayoutPreset string
+
+const (
+	// EvenHorizontal gives the same width to panes that are all side by side.
+	EvenHorizontal LayoutPreset = "even-horizontal"
+	// EvenVertical gives the same height to panes that are all stacked.
+	EvenVertical LayoutPreset = "even-vertical"
+	// MainVertical gives MainPaneWidth cells to the left pane, and the same height to the panes stacked on its right.
+	MainVertical LayoutPreset = "main-vertical"
+	// Tiled gives the same size to all the panes sharing a split, at every level of the tab's split tree.
+	Tiled LayoutPreset = "tiled"
+)
+
+// MainPaneWidth is the width of the main pane of the MainVertical preset, or half of the tab if it's narrower.
+const MainPaneWidth = 80
+
+// Tab is a tab of a terminal window. Get one with App.Tab.
+type Tab struct {
+	id   string
+	app  *App
+	conn *Connection
+}
+
+// Id returns the tab's ID.
+func (t *Tab) Id() string {
+	return t.id
+}
+
+// SessionIds returns the IDs of the tab's sessions, in the order they're laid out.
+func (t *Tab) SessionIds() ([]string, error) {
+	root, err := t.splitTree()
+	if err != nil {
+		return nil, fmt.Errorf("session ids: %w", err)
+	}
+
+	var sessionIds []string
+	for _, s := range splitTreeSessions(root) {
+		sessionIds = append(sessionIds, s.GetUniqueIdentifier())
+	}
+
+	return sessionIds, nil
+}
+
+// splitTree returns the current split tree of the tab's panes.
+func (t *Tab) splitTree() (*iterm2.SplitTreeNode, error) {
+	resp, err := t.app.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, win := range resp.GetWindows() {
+		for _, tab := range win.GetTabs() {
+			if tab.GetTabId() == t.id {
+				return tab.GetRoot(), nil
+			}
+		}
+	}
+
+	return nil, ErrTabNotFound
+}
+
+// setSplitTree resizes the tab's panes. The tree must have the same structure as the one returned by splitTree, and
+// the sizes must add up to the same values in every dimension.
+func (t *Tab) setSplitTree(root *iterm2.SplitTreeNode) error {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_SetTabLayoutRequest{
+			SetTabLayoutRequest: &iterm2.SetTabLayoutRequest{Root: root, TabId: &t.id},
+		},
+	}
+
+	resp, err := t.conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	switch resp.GetSetTabLayoutResponse().GetStatus() {
+	case iterm2.SetTabLayoutResponse_OK:
+		return nil
+	case iterm2.SetTabLayoutResponse_BAD_TAB_ID:
+		return ErrTabNotFound
+	case iterm2.SetTabLayoutResponse_WRONG_TREE:
+		return ErrWrongSplitTree
+	case iterm2.SetTabLayoutResponse_INVALID_SIZE:
+		return ErrInvalidPaneSize
+	default:
+		return fmt.Errorf("%s", resp.GetSetTabLayoutResponse().GetStatus())
+	}
+}
+
+// ResizePane grows the pane of the given session by a number of cells towards the given direction, shrinking the
+// adjacent pane; negative cells shrink the pane instead. When the session is part of a split that has no adjacent pane
+// in that direction, the whole split is resized. ErrNoAdjacentPane is returned when the pane is at the tab's border.
+func (t *Tab) ResizePane(sessionId string, direction ResizeDirection, cells int32) error {
+	root, err := t.splitTree()
+	if err != nil {
+		return fmt.Errorf("resize pane: %w", err)
+	}
+
+	path := splitTreePath(root, sessionId)
+	if path == nil {
+		return fmt.Errorf("resize pane: %w: %s", ErrPaneNotFound, sessionId)
+	}
+
+	width := direction == ResizeLeft || direction == ResizeRight
+	step := 1
+	if direction == ResizeLeft || direction == ResizeUp {
+		step = -1
+	}
+
+	for i := len(path) - 1; i >= 0; i-- {
+		node, index := path[i].node, path[i].index
+		neighbor := index + step
+
+		if node.GetVertical() != width || neighbor < 0 || neighbor >= len(node.GetLinks()) {
+			continue
+		}
+
+		pane := node.GetLinks()[index]
+		adjacent := node.GetLinks()[neighbor]
+		paneSize := linkExtent(pane, width) + cells
+		adjacentSize := linkExtent(adjacent, width) - cells
+
+		if paneSize < 1 || adjacentSize < 1 {
+			return fmt.Errorf("resize pane: %w: cannot resize by %d cells", ErrInvalidPaneSize, cells)
+		}
+
+		setLinkExtent(pane, width, paneSize)
+		setLinkExtent(adjacent, width, adjacentSize)
+
+		if err := t.setSplitTree(root); err != nil {
+			return fmt.Errorf("resize pane: %w", err)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("resize pane: %w", ErrNoAdjacentPane)
+}
+
+// SetProportions shares the tab's size among the panes (or splits) at the top of its split tree, proportionally to the
+// given weights: side by side panes are resized in width, stacked ones in height.
+func (t *Tab) SetProportions(weights ...float64) error {
+	root, err := t.splitTree()
+	if err != nil {
+		return fmt.Errorf("set proportions: %w", err)
+	}
+
+	if len(weights) != len(root.GetLinks()) {
+		return fmt.Errorf("set proportions: %w: %d panes, got %d proportions", ErrIncompatibleLayout,
+			len(root.GetLinks()), len(weights))
+	}
+
+	for _, w := range weights {
+		if w <= 0 {
+			return fmt.Errorf("set proportions: %w: %v", ErrInvalidPaneSize, w)
+		}
+	}
+
+	resizeNode(root, weights)
+
+	if err := t.setSplitTree(root); err != nil {
+		return fmt.Errorf("set proportions: %w", err)
+	}
+
+	return nil
+}
+
+// ApplyPreset resizes the tab's panes according to a LayoutPreset.
+func (t *Tab) ApplyPreset(preset LayoutPreset) error {
+	root, err := t.splitTree()
+	if err != nil {
+		return fmt.Errorf("apply preset %s: %w", preset, err)
+	}
+
+	if err := preset.Apply(root); err != nil {
+		return err
+	}
+
+	if err := t.setSplitTree(root); err != nil {
+		return fmt.Errorf("apply preset %s: %w", preset, err)
+	}
+
+	return nil
+}
+
+// Apply sets the sizes of the sessions in a tab's split tree, such as the one found in a ListSessionsResponse, without
+// changing the sum of the sizes in any dimension. ErrIncompatibleLayout is returned when the tree can't be laid out as
+// the preset requires.
+func (p LayoutPreset) Apply(root *iterm2.SplitTreeNode) error {
+	if len(root.GetLinks()) < 2 {
+		return nil
+	}
+
+	switch p {
+	case EvenHorizontal:
+		return p.applyEven(root, true)
+	case EvenVertical:
+		return p.applyEven(root, false)
+	case MainVertical:
+		return p.applyMainVertical(root)
+	case Tiled:
+		applyTiled(root)
+		return nil
+	default:
+		return fmt.Errorf("apply preset %s: unknown preset", p)
+	}
+}
+
+func (p LayoutPreset) applyEven(root *iterm2.SplitTreeNode, vertical bool) error {
+	if root.GetVertical() != vertical || !allSessions(root) {
+		arrangement := "stacked"
+		if vertical {
+			arrangement = "side by side"
+		}
+
+		return fmt.Errorf("apply preset %s: %w: panes are not all %s", p, ErrIncompatibleLayout, arrangement)
+	}
+
+	resizeNode(root, evenWeights(len(root.GetLinks())))
+	return nil
+}
+
+func (p LayoutPreset) applyMainVertical(root *iterm2.SplitTreeNode) error {
+	links := root.GetLinks()
+
+	if !root.GetVertical() || len(links) != 2 || links[0].GetSession() == nil ||
+		(links[1].GetNode() != nil && (links[1].GetNode().GetVertical() || !allSessions(links[1].GetNode()))) {
+
+		return fmt.Errorf("apply preset %s: %w: expected a pane on the left of a stack of panes", p,
+			ErrIncompatibleLayout)
+	}
+
+	total := nodeExtent(root, true)
+	main := int32(MainPaneWidth)
+	if main >= total {
+		main = total / 2
+	}
+
+	setLinkExtent(links[0], true, main)
+	setLinkExtent(links[1], true, total-main)
+
+	if stack := links[1].GetNode(); stack != nil {
+		resizeNode(stack, evenWeights(len(stack.GetLinks())))
+	}
+
+	return nil
+}
+
+func applyTiled(node *iterm2.SplitTreeNode) {
+	resizeNode(node, evenWeights(len(node.GetLinks())))
+
+	for _, link := range node.GetLinks() {
+		if link.GetNode() != nil {
+			applyTiled(link.GetNode())
+		}
+	}
+}
+
+func allSessions(node *iterm2.SplitTreeNode) bool {
+	for _, link := range node.GetLinks() {
+		if link.GetSession() == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func evenWeights(n int) []float64 {
+	weights := make([]float64, n)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return weights
+}
+
+type splitTreeStep struct {
+	node  *iterm2.SplitTreeNode
+	index int
+}
+
+// splitTreePath returns the nodes leading to a session in a split tree, with the index of the link followed in each,
+// or nil if the session is not in the tree.
+func splitTreePath(node *iterm2.SplitTreeNode, sessionId string) []splitTreeStep {
+	for i, link := range node.GetLinks() {
+		if link.GetSession() != nil && link.GetSession().GetUniqueIdentifier() == sessionId {
+			return []splitTreeStep{{node: node, index: i}}
+		}
+
+		if link.GetNode() != nil {
+			if path := splitTreePath(link.GetNode(), sessionId); path != nil {
+				return append([]splitTreeStep{{node: node, index: i}}, path...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// resizeNode shares the size of a split tree node among its children, proportionally to the given weights. Children
+// of a vertical node (with a vertical divider) are resized in width, the others in height.
+func resizeNode(node *iterm2.SplitTreeNode, weights []float64) {
+	width := node.GetVertical()
+	sizes := distribute(nodeExtent(node, width), weights)
+
+	for i, link := range node.GetLinks() {
+		setLinkExtent(link, width, sizes[i])
+	}
+}
+
+// nodeExtent returns the size in cells of a split tree node, along the width or the height.
+func nodeExtent(node *iterm2.SplitTreeNode, width bool) int32 {
+	var extent int32
+
+	for _, link := range node.GetLinks() {
+		e := linkExtent(link, width)
+		if node.GetVertical() == width {
+			extent += e
+		} else if e > extent {
+			extent = e
+		}
+	}
+
+	return extent
+}
+
+func linkExtent(link *iterm2.SplitTreeNode_SplitTreeLink, width bool) int32 {
+	if s := link.GetSession(); s != nil {
+		if width {
+			return s.GetGridSize().GetWidth()
+		}
+		return s.GetGridSize().GetHeight()
+	}
+
+	return nodeExtent(link.GetNode(), width)
+}
+
+// setLinkExtent resizes a session or a subtree along the width or the height. Subtrees split along the same dimension
+// keep the proportions of their children.
+func setLinkExtent(link *iterm2.SplitTreeNode_SplitTreeLink, width bool, extent int32) {
+	if s := link.GetSession(); s != nil {
+		if s.GridSize == nil {
+			s.GridSize = &iterm2.Size{}
+		}
+
+		value := extent
+		if width {
+			s.GridSize.Width = &value
+		} else {
+			s.GridSize.Height = &value
+		}
+		return
+	}
+
+	node := link.GetNode()
+
+	if node.GetVertical() != width {
+		for _, child := range node.GetLinks() {
+			setLinkExtent(child, width, extent)
+		}
+		return
+	}
+
+	var weights []float64
+	for _, child := range node.GetLinks() {
+		weights = append(weights, float64(linkExtent(child, width)))
+	}
+
+	for i, size := range distribute(extent, weights) {
+		setLinkExtent(node.GetLinks()[i], width, size)
+	}
+}
+
+// distribute splits total in integer parts proportional to the given weights, each at least 1, giving the rounding
+// leftovers to the parts with the largest remainders. Non-positive weights count as zero, and the parts are equal
+// when no weight is positive.
+func distribute(total int32, weights []float64) []int32 {
+	sum := 0.0
+	for _, w := range weights {
+		sum += math.Max(w, 0)
+	}
+
+	n := len(weights)
+	sizes := make([]int32, n)
+	remainders := make([]float64, n)
+
+	available := float64(total - int32(n))
+	if available < 0 {
+		available = 0
+	}
+
+	assigned := int32(0)
+
+	for i, w := range weights {
+		share := available / float64(n)
+		if sum > 0 {
+			share = available * math.Max(w, 0) / sum
+		}
+
+		sizes[i] = 1 + int32(math.Floor(share))
+		remainders[i] = share - math.Floor(share)
+		assigned += sizes[i]
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+
+	for i := 0; assigned < total && n > 0; i++ {
+		sizes[order[i%n]]++
+		assigned++
+	}
+
+	return sizes
+}
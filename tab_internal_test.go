@@ -0,0 +1,30 @@
+package itermctl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDistribute(t *testing.T) {
+	examples := []struct {
+		total    int32
+		weights  []float64
+		expected []int32
+	}{
+		{total: 10, weights: []float64{1, 1}, expected: []int32{5, 5}},
+		{total: 10, weights: []float64{3, 1}, expected: []int32{7, 3}},
+		{total: 10, weights: []float64{1, 1, 1}, expected: []int32{4, 3, 3}},
+		{total: 10, weights: []float64{0, 0}, expected: []int32{5, 5}},
+		{total: 10, weights: []float64{-1, -3}, expected: []int32{5, 5}},
+		{total: 10, weights: []float64{2, -1}, expected: []int32{9, 1}},
+		{total: 10, weights: []float64{-2, 1, 1}, expected: []int32{1, 5, 4}},
+		{total: 2, weights: []float64{1, 1, 1}, expected: []int32{1, 1, 1}},
+	}
+
+	for _, ex := range examples {
+		sizes := distribute(ex.total, ex.weights)
+		if !reflect.DeepEqual(sizes, ex.expected) {
+			t.Errorf("distribute(%d, %v): expected %v, got %v", ex.total, ex.weights, ex.expected, sizes)
+		}
+	}
+}
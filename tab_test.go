@@ -0,0 +1,119 @@
+package itermctl_test
+
+import (
+	"errors"
+	"mrz.io/itermctl"
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"testing"
+)
+
+func testSession(id string, width, height int32) *iterm2.SplitTreeNode_SplitTreeLink {
+	return &iterm2.SplitTreeNode_SplitTreeLink{
+		Child: &iterm2.SplitTreeNode_SplitTreeLink_Session{
+			Session: &iterm2.SessionSummary{
+				UniqueIdentifier: &id,
+				GridSize:         &iterm2.Size{Width: &width, Height: &height},
+			},
+		},
+	}
+}
+
+func testNode(vertical bool, links ...*iterm2.SplitTreeNode_SplitTreeLink) *iterm2.SplitTreeNode {
+	return &iterm2.SplitTreeNode{Vertical: &vertical, Links: links}
+}
+
+func testSplit(vertical bool, links ...*iterm2.SplitTreeNode_SplitTreeLink) *iterm2.SplitTreeNode_SplitTreeLink {
+	return &iterm2.SplitTreeNode_SplitTreeLink{
+		Child: &iterm2.SplitTreeNode_SplitTreeLink_Node{Node: testNode(vertical, links...)},
+	}
+}
+
+// testSizes returns the "width x height" of every session of a split tree, in layout order.
+func testSizes(node *iterm2.SplitTreeNode) [][2]int32 {
+	var sizes [][2]int32
+
+	for _, link := range node.GetLinks() {
+		if s := link.GetSession(); s != nil {
+			sizes = append(sizes, [2]int32{s.GetGridSize().GetWidth(), s.GetGridSize().GetHeight()})
+		} else {
+			sizes = append(sizes, testSizes(link.GetNode())...)
+		}
+	}
+
+	return sizes
+}
+
+func TestLayoutPreset_Apply(t *testing.T) {
+	examples := []struct {
+		preset   itermctl.LayoutPreset
+		tree     *iterm2.SplitTreeNode
+		expected [][2]int32
+	}{
+		{
+			preset:   itermctl.EvenHorizontal,
+			tree:     testNode(true, testSession("a", 10, 40), testSession("b", 50, 40), testSession("c", 41, 40)),
+			expected: [][2]int32{{34, 40}, {34, 40}, {33, 40}},
+		},
+		{
+			preset:   itermctl.EvenVertical,
+			tree:     testNode(false, testSession("a", 100, 30), testSession("b", 100, 10)),
+			expected: [][2]int32{{100, 20}, {100, 20}},
+		},
+		{
+			preset: itermctl.MainVertical,
+			tree: testNode(true, testSession("a", 100, 40),
+				testSplit(false, testSession("b", 100, 10), testSession("c", 100, 30))),
+			expected: [][2]int32{{80, 40}, {120, 20}, {120, 20}},
+		},
+		{
+			preset:   itermctl.MainVertical,
+			tree:     testNode(true, testSession("a", 30, 40), testSession("b", 70, 40)),
+			expected: [][2]int32{{80, 40}, {20, 40}},
+		},
+		{
+			preset: itermctl.Tiled,
+			tree: testNode(true, testSession("a", 20, 40),
+				testSplit(false, testSession("b", 80, 10), testSession("c", 80, 30))),
+			expected: [][2]int32{{50, 40}, {50, 20}, {50, 20}},
+		},
+	}
+
+	for _, example := range examples {
+		t.Run(string(example.preset), func(t *testing.T) {
+			if err := example.preset.Apply(example.tree); err != nil {
+				t.Fatal(err)
+			}
+
+			if sizes := testSizes(example.tree); !reflect.DeepEqual(sizes, example.expected) {
+				t.Fatalf("expected %v, got %v", example.expected, sizes)
+			}
+		})
+	}
+}
+
+func TestLayoutPreset_Apply_Incompatible(t *testing.T) {
+	examples := []struct {
+		preset itermctl.LayoutPreset
+		tree   *iterm2.SplitTreeNode
+	}{
+		{preset: itermctl.EvenHorizontal, tree: testNode(false, testSession("a", 10, 10), testSession("b", 10, 10))},
+		{
+			preset: itermctl.EvenVertical,
+			tree: testNode(false, testSession("a", 10, 10),
+				testSplit(true, testSession("b", 5, 10), testSession("c", 5, 10))),
+		},
+		{
+			preset: itermctl.MainVertical,
+			tree:   testNode(true, testSession("a", 10, 10), testSession("b", 10, 10), testSession("c", 10, 10)),
+		},
+	}
+
+	for _, example := range examples {
+		t.Run(string(example.preset), func(t *testing.T) {
+			if err := example.preset.Apply(example.tree); !errors.Is(err, itermctl.ErrIncompatibleLayout) {
+				t.Fatalf("expected %v, got %v", itermctl.ErrIncompatibleLayout, err)
+			}
+		})
+	}
+}
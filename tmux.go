@@ -0,0 +1,256 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+	"strings"
+)
+
+var (
+	ErrTmuxInvalidRequest      = fmt.Errorf("TmuxResponse_INVALID_REQUEST")
+	ErrTmuxInvalidConnectionId = fmt.Errorf("TmuxResponse_INVALID_CONNECTION_ID")
+	ErrTmuxInvalidWindowId     = fmt.Errorf("TmuxResponse_INVALID_WINDOW_ID")
+	ErrTmuxCommandFailed       = fmt.Errorf("tmux command failed")
+)
+
+// tmuxFieldSeparator separates the fields requested by TmuxConnection.Query; tmux copies it verbatim.
+const tmuxFieldSeparator = "\t"
+
+// TmuxConnection is a tmux -CC integration session, attached through the session that owns it.
+// See https://iterm2.com/python-api/tmux.html.
+type TmuxConnection struct {
+	Id              string
+	OwningSessionId string
+
+	app *App
+}
+
+// TmuxWindow is a window of a tmux connection. TabId is the ID of the iTerm2 tab showing it, empty if it's hidden.
+type TmuxWindow struct {
+	Id    string
+	Name  string
+	TabId string
+}
+
+// TmuxConnections lists the current tmux integration connections.
+func (a *App) TmuxConnections() ([]*TmuxConnection, error) {
+	resp, err := sendTmuxRequest(context.Background(), a.conn, &iterm2.TmuxRequest{
+		Payload: &iterm2.TmuxRequest_ListConnections_{ListConnections: &iterm2.TmuxRequest_ListConnections{}},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("tmux connections: %w", err)
+	}
+
+	var connections []*TmuxConnection
+	for _, c := range resp.GetListConnections().GetConnections() {
+		connections = append(connections, &TmuxConnection{
+			Id:              c.GetConnectionId(),
+			OwningSessionId: c.GetOwningSessionId(),
+			app:             a,
+		})
+	}
+
+	return connections, nil
+}
+
+// OwningSession returns the session where tmux -CC was started.
+func (c *TmuxConnection) OwningSession() *Session {
+	return newSession(c.OwningSessionId, c.app, c.app.conn, false)
+}
+
+// Command runs a tmux command, such as "list-panes -a", and returns its output lines.
+func (c *TmuxConnection) Command(ctx context.Context, command string) ([]string, error) {
+	resp, err := sendTmuxRequest(ctx, c.app.conn, &iterm2.TmuxRequest{
+		Payload: &iterm2.TmuxRequest_SendCommand_{
+			SendCommand: &iterm2.TmuxRequest_SendCommand{ConnectionId: &c.Id, Command: &command},
+		},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("tmux command %q: %w", command, err)
+	}
+
+	if resp.GetSendCommand().Output == nil {
+		return nil, fmt.Errorf("tmux command %q: %w", command, ErrTmuxCommandFailed)
+	}
+
+	return tmuxOutputLines(resp.GetSendCommand().GetOutput()), nil
+}
+
+// Query runs a tmux listing command, such as "list-panes -a", with a format made of the given fields, such as
+// "pane_id" or "pane_current_path", and returns one map of field values per line.
+// See the FORMATS section of tmux(1).
+func (c *TmuxConnection) Query(ctx context.Context, command string, fields ...string) ([]map[string]string, error) {
+	lines, err := c.Command(ctx, tmuxQueryCommand(command, fields))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseTmuxRows(command, fields, lines)
+}
+
+// tmuxQueryCommand appends to a tmux listing command the format printing the given fields, separated by
+// tmuxFieldSeparator.
+func tmuxQueryCommand(command string, fields []string) string {
+	var format []string
+	for _, f := range fields {
+		format = append(format, "#{"+f+"}")
+	}
+
+	return fmt.Sprintf("%s -F '%s'", command, strings.Join(format, tmuxFieldSeparator))
+}
+
+// parseTmuxRows parses the output lines of a command built by tmuxQueryCommand.
+func parseTmuxRows(command string, fields []string, lines []string) ([]map[string]string, error) {
+	var rows []map[string]string
+
+	for _, line := range lines {
+		values := strings.SplitN(line, tmuxFieldSeparator, len(fields))
+		if len(values) != len(fields) {
+			return nil, fmt.Errorf("tmux command %q: expected %d fields, got %q", command, len(fields), line)
+		}
+
+		row := make(map[string]string)
+		for i, f := range fields {
+			row[f] = values[i]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Windows lists the connection's tmux windows, including the hidden ones.
+func (c *TmuxConnection) Windows(ctx context.Context) ([]TmuxWindow, error) {
+	rows, err := c.Query(ctx, "list-windows -a", "window_id", "window_name")
+	if err != nil {
+		return nil, err
+	}
+
+	tabs, err := c.Tabs()
+	if err != nil {
+		return nil, err
+	}
+
+	return newTmuxWindows(rows, tabs), nil
+}
+
+// newTmuxWindows builds TmuxWindows from the rows of "list-windows" and the tabs returned by TmuxConnection.Tabs.
+// tmux prefixes window IDs with "@", iTerm2 doesn't.
+func newTmuxWindows(rows []map[string]string, tabs map[string]string) []TmuxWindow {
+	var windows []TmuxWindow
+	for _, row := range rows {
+		id := strings.TrimPrefix(row["window_id"], "@")
+		windows = append(windows, TmuxWindow{Id: id, Name: row["window_name"], TabId: tabs[id]})
+	}
+
+	return windows
+}
+
+// Tabs maps the IDs of the connection's visible tmux windows to the IDs of the iTerm2 tabs showing them.
+func (c *TmuxConnection) Tabs() (map[string]string, error) {
+	layout, err := c.app.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("tmux tabs: %w", err)
+	}
+
+	return tmuxTabs(layout, c.Id), nil
+}
+
+func tmuxTabs(layout *iterm2.ListSessionsResponse, connectionId string) map[string]string {
+	tabs := make(map[string]string)
+
+	for _, win := range layout.GetWindows() {
+		for _, tab := range win.GetTabs() {
+			if tab.GetTmuxConnectionId() == connectionId && tab.GetTmuxWindowId() != "" {
+				tabs[tab.GetTmuxWindowId()] = tab.GetTabId()
+			}
+		}
+	}
+
+	return tabs
+}
+
+// SetWindowVisible shows or hides a tmux window. Hidden windows keep running in tmux, without an iTerm2 tab.
+func (c *TmuxConnection) SetWindowVisible(windowId string, visible bool) error {
+	_, err := sendTmuxRequest(context.Background(), c.app.conn, &iterm2.TmuxRequest{
+		Payload: &iterm2.TmuxRequest_SetWindowVisible_{
+			SetWindowVisible: &iterm2.TmuxRequest_SetWindowVisible{
+				ConnectionId: &c.Id,
+				WindowId:     &windowId,
+				Visible:      &visible,
+			},
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("tmux set window visible: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWindow creates a tmux window and returns the iTerm2 tab showing it. If affinity is the ID of a tmux window,
+// the new tab is created in the same iTerm2 window; otherwise a new iTerm2 window is created.
+func (c *TmuxConnection) CreateWindow(affinity string) (*Tab, error) {
+	createReq := &iterm2.TmuxRequest_CreateWindow{ConnectionId: &c.Id}
+	if affinity != "" {
+		createReq.Affinity = &affinity
+	}
+
+	resp, err := sendTmuxRequest(context.Background(), c.app.conn, &iterm2.TmuxRequest{
+		Payload: &iterm2.TmuxRequest_CreateWindow_{CreateWindow: createReq},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("tmux create window: %w", err)
+	}
+
+	return c.app.Tab(resp.GetCreateWindow().GetTabId()), nil
+}
+
+func sendTmuxRequest(ctx context.Context, conn *Connection, tmuxReq *iterm2.TmuxRequest) (*iterm2.TmuxResponse, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_TmuxRequest{
+			TmuxRequest: tmuxReq,
+		},
+	}
+
+	resp, err := conn.GetResponse(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tmuxStatusError(resp.GetTmuxResponse().GetStatus()); err != nil {
+		return nil, err
+	}
+
+	return resp.GetTmuxResponse(), nil
+}
+
+func tmuxStatusError(status iterm2.TmuxResponse_Status) error {
+	switch status {
+	case iterm2.TmuxResponse_OK:
+		return nil
+	case iterm2.TmuxResponse_INVALID_REQUEST:
+		return ErrTmuxInvalidRequest
+	case iterm2.TmuxResponse_INVALID_CONNECTION_ID:
+		return ErrTmuxInvalidConnectionId
+	case iterm2.TmuxResponse_INVALID_WINDOW_ID:
+		return ErrTmuxInvalidWindowId
+	default:
+		return fmt.Errorf("%s", status)
+	}
+}
+
+// tmuxOutputLines splits the output of a tmux command in lines, ignoring the trailing newlines.
+func tmuxOutputLines(output string) []string {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil
+	}
+
+	return strings.Split(output, "\n")
+}
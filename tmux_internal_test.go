@@ -0,0 +1,102 @@
+package itermctl
+
+import (
+	"github.com/golang/protobuf/proto"
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"testing"
+)
+
+func TestTmuxStatusError(t *testing.T) {
+	examples := map[iterm2.TmuxResponse_Status]error{
+		iterm2.TmuxResponse_OK:                    nil,
+		iterm2.TmuxResponse_INVALID_REQUEST:       ErrTmuxInvalidRequest,
+		iterm2.TmuxResponse_INVALID_CONNECTION_ID: ErrTmuxInvalidConnectionId,
+		iterm2.TmuxResponse_INVALID_WINDOW_ID:     ErrTmuxInvalidWindowId,
+	}
+
+	for status, expected := range examples {
+		if err := tmuxStatusError(status); err != expected {
+			t.Errorf("%s: expected %v, got %v", status, expected, err)
+		}
+	}
+
+	if err := tmuxStatusError(iterm2.TmuxResponse_Status(42)); err == nil {
+		t.Error("expected an error for an unknown status")
+	}
+}
+
+func TestTmuxOutputLines(t *testing.T) {
+	examples := map[string][]string{
+		"":           nil,
+		"\n":         nil,
+		"a":          {"a"},
+		"a\nb\n":     {"a", "b"},
+		"a\n\nb\n\n": {"a", "", "b"},
+	}
+
+	for output, expected := range examples {
+		if lines := tmuxOutputLines(output); !reflect.DeepEqual(lines, expected) {
+			t.Errorf("%q: expected %q, got %q", output, expected, lines)
+		}
+	}
+}
+
+func TestTmuxQuery(t *testing.T) {
+	fields := []string{"window_id", "window_name"}
+
+	command := tmuxQueryCommand("list-windows -a", fields)
+	if expected := "list-windows -a -F '#{window_id}\t#{window_name}'"; command != expected {
+		t.Fatalf("expected %q, got %q", expected, command)
+	}
+
+	rows, err := parseTmuxRows("list-windows -a", fields, []string{"@1\tvim", "@2\tname\twith tab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []map[string]string{
+		{"window_id": "@1", "window_name": "vim"},
+		{"window_id": "@2", "window_name": "name\twith tab"},
+	}
+
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("expected %v, got %v", expected, rows)
+	}
+
+	if _, err := parseTmuxRows("list-windows -a", fields, []string{"@1"}); err == nil {
+		t.Fatal("expected an error for a line with missing fields")
+	}
+}
+
+func TestTmuxWindows(t *testing.T) {
+	tab := func(tabId, connectionId, windowId string) *iterm2.ListSessionsResponse_Tab {
+		return &iterm2.ListSessionsResponse_Tab{
+			TabId:            proto.String(tabId),
+			TmuxConnectionId: proto.String(connectionId),
+			TmuxWindowId:     proto.String(windowId),
+		}
+	}
+
+	layout := &iterm2.ListSessionsResponse{
+		Windows: []*iterm2.ListSessionsResponse_Window{
+			{Tabs: []*iterm2.ListSessionsResponse_Tab{tab("t1", "c1", "1"), tab("t2", "", "")}},
+			{Tabs: []*iterm2.ListSessionsResponse_Tab{tab("t3", "c1", "3"), tab("t4", "c2", "1")}},
+		},
+	}
+
+	tabs := tmuxTabs(layout, "c1")
+	if expected := map[string]string{"1": "t1", "3": "t3"}; !reflect.DeepEqual(tabs, expected) {
+		t.Fatalf("expected %v, got %v", expected, tabs)
+	}
+
+	windows := newTmuxWindows([]map[string]string{
+		{"window_id": "@1", "window_name": "vim"},
+		{"window_id": "@2", "window_name": "hidden"},
+	}, tabs)
+
+	expected := []TmuxWindow{{Id: "1", Name: "vim", TabId: "t1"}, {Id: "2", Name: "hidden"}}
+	if !reflect.DeepEqual(windows, expected) {
+		t.Fatalf("expected %v, got %v", expected, windows)
+	}
+}
@@ -0,0 +1,394 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"mrz.io/itermctl/internal/json"
+	"mrz.io/itermctl/iterm2"
+	"sort"
+	"sync"
+)
+
+// VariableChange describes a change of a variable's value. Values are JSON encoded, with "null" for unset variables.
+type VariableChange struct {
+	Scope      iterm2.VariableScope
+	Identifier string
+	Name       string
+	OldValue   string
+	NewValue   string
+}
+
+// Values unmarshals the old and new values into the given targets. Either target can be nil.
+func (c VariableChange) Values(oldTarget, newTarget interface{}) error {
+	if oldTarget != nil {
+		if err := json.UnmarshalString(c.OldValue, oldTarget); err != nil {
+			return fmt.Errorf("%s: old value: %w", c.Name, err)
+		}
+	}
+
+	if newTarget != nil {
+		if err := json.UnmarshalString(c.NewValue, newTarget); err != nil {
+			return fmt.Errorf("%s: new value: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Strings returns the old and new values as strings, in the form used by SessionState.Variable.
+func (c VariableChange) Strings() (string, string, error) {
+	oldValue, err := variableString(c.OldValue)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: old value: %w", c.Name, err)
+	}
+
+	newValue, err := variableString(c.NewValue)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: new value: %w", c.Name, err)
+	}
+
+	return oldValue, newValue, nil
+}
+
+// MonitorVariable subscribes to VariableChangedNotifications for the named variable of the session, tab or window with
+// the given identifier (the identifier is ignored for the APP scope), and writes a VariableChange to the returned
+// channel each time the value changes, until the context is done or the Connection is closed.
+// See https://iterm2.com/python-api/variables.html#iterm2.VariableMonitor.
+func MonitorVariable(ctx context.Context, conn *Connection, scope iterm2.VariableScope, identifier string, name string) (<-chan VariableChange, error) {
+	_, changes, err := monitorVariable(ctx, conn, scope, identifier, name)
+	return changes, err
+}
+
+// monitorVariable is MonitorVariable, returning also the value of the variable at the time of subscription.
+func monitorVariable(ctx context.Context, conn *Connection, scope iterm2.VariableScope, identifier string, name string) (string, <-chan VariableChange, error) {
+	if scope == iterm2.VariableScope_APP {
+		identifier = ""
+	}
+
+	monitorReq := &iterm2.VariableMonitorRequest{Name: &name, Scope: &scope}
+	if identifier != "" {
+		monitorReq.Identifier = &identifier
+	}
+
+	req := NewNotificationRequest(true, iterm2.NotificationType_NOTIFY_ON_VARIABLE_CHANGE, "")
+	req.Arguments = &iterm2.NotificationRequest_VariableMonitorRequest{VariableMonitorRequest: monitorReq}
+
+	// cancelled on error, so that the subscription doesn't hold up the Connection
+	ctx, cancel := context.WithCancel(ctx)
+
+	recv, err := conn.Subscribe(ctx, req)
+	if err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("variable monitor: %w", err)
+	}
+
+	values, err := getVariables(conn, newVariableScope(scope, identifier), name)
+	if err != nil {
+		cancel()
+		return "", nil, fmt.Errorf("variable monitor: %w", err)
+	}
+
+	initialValue := values[0]
+	changes := make(chan VariableChange)
+
+	go func() {
+		defer cancel()
+		lastValue := initialValue
+
+		for msg := range recv.Ch() {
+			n := msg.GetNotification().GetVariableChangedNotification()
+			if n == nil || n.GetScope() != scope || n.GetName() != name || n.GetIdentifier() != identifier {
+				continue
+			}
+
+			if n.GetJsonNewValue() == lastValue {
+				continue
+			}
+
+			change := VariableChange{
+				Scope:      scope,
+				Identifier: identifier,
+				Name:       name,
+				OldValue:   lastValue,
+				NewValue:   n.GetJsonNewValue(),
+			}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+			}
+
+			lastValue = n.GetJsonNewValue()
+		}
+
+		close(changes)
+	}()
+
+	return initialValue, changes, nil
+}
+
+// VariableWatcher keeps track of a set of session variables across all sessions, including the ones created after it
+// started. It is also a SessionSource, serving the last known values of the watched variables without talking to
+// iTerm2; its SessionStates' Title and Focused are not kept up to date. Create one with WatchVariables.
+type VariableWatcher struct {
+	conn    *Connection
+	names   []string
+	changes chan VariableChange
+
+	mx       *sync.Mutex
+	sessions map[string]*watchedSession
+	wg       *sync.WaitGroup
+}
+
+type watchedSession struct {
+	state  *SessionState
+	cancel context.CancelFunc
+}
+
+// WatchVariables starts watching the named variables (eg. "jobName", "path" or "user.gitBranch") of all sessions,
+// until the context is done or the Connection is closed. Changes are written to the watcher's Changes channel: a new
+// session results in one change per variable, from null to its initial value; terminated sessions are forgotten.
+func WatchVariables(ctx context.Context, app *App, names ...string) (*VariableWatcher, error) {
+	w := newVariableWatcher(app.conn, names...)
+
+	// cancelled on error, so that the subscriptions made so far don't hold up the Connection
+	ctx, cancel := context.WithCancel(ctx)
+
+	newSessions, err := MonitorNewSessions(ctx, app.conn)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("watch variables: %w", err)
+	}
+
+	terminatedSessions, err := MonitorSessionsTermination(ctx, app.conn)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("watch variables: %w", err)
+	}
+
+	layoutRecv, err := app.conn.Subscribe(ctx,
+		NewNotificationRequest(true, iterm2.NotificationType_NOTIFY_ON_LAYOUT_CHANGE, ""))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("watch variables: %w", err)
+	}
+
+	states, err := app.SessionStates()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("watch variables: %w", err)
+	}
+
+	// the main loop holds one count of the WaitGroup, so that watching new sessions can safely add more
+	w.wg.Add(1)
+
+	for _, state := range states {
+		w.watch(ctx, state, false)
+	}
+
+	layoutChanges := layoutRecv.Ch()
+
+	go func() {
+		defer cancel()
+		defer w.wg.Done()
+
+		for newSessions != nil || terminatedSessions != nil || layoutChanges != nil {
+			select {
+			case n, ok := <-newSessions:
+				if !ok {
+					newSessions = nil
+					continue
+				}
+				w.watch(ctx, &SessionState{SessionId: n.GetSessionId()}, true)
+
+			case n, ok := <-terminatedSessions:
+				if !ok {
+					terminatedSessions = nil
+					continue
+				}
+				w.forget(n.GetSessionId())
+
+			case msg, ok := <-layoutChanges:
+				if !ok {
+					layoutChanges = nil
+					continue
+				}
+				w.applyLayout(msg.GetNotification().GetLayoutChangedNotification().GetListSessionsResponse())
+			}
+		}
+	}()
+
+	go func() {
+		w.wg.Wait()
+		close(w.changes)
+	}()
+
+	return w, nil
+}
+
+func newVariableWatcher(conn *Connection, names ...string) *VariableWatcher {
+	return &VariableWatcher{
+		conn:     conn,
+		names:    names,
+		changes:  make(chan VariableChange),
+		mx:       &sync.Mutex{},
+		sessions: make(map[string]*watchedSession),
+		wg:       &sync.WaitGroup{},
+	}
+}
+
+// Changes returns the channel where changes of the watched variables are written. It's closed when the watcher stops.
+func (w *VariableWatcher) Changes() <-chan VariableChange {
+	return w.changes
+}
+
+// SessionStates returns a copy of the watched sessions' last known state.
+func (w *VariableWatcher) SessionStates() ([]*SessionState, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	var states []*SessionState
+
+	for _, s := range w.sessions {
+		state := *s.state
+		state.Variables = make(map[string]string)
+		for name, value := range s.state.Variables {
+			state.Variables[name] = value
+		}
+		states = append(states, &state)
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].SessionId < states[j].SessionId
+	})
+
+	return states, nil
+}
+
+// Value returns the last known value of a watched variable of a session, in the form used by SessionState.Variable.
+func (w *VariableWatcher) Value(sessionId, name string) (string, bool) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	s, ok := w.sessions[sessionId]
+	if !ok {
+		return "", false
+	}
+
+	value, ok := s.state.Variables[name]
+	return value, ok
+}
+
+func (w *VariableWatcher) watch(ctx context.Context, state *SessionState, announce bool) {
+	sessionCtx, ok := w.add(ctx, state)
+	if !ok {
+		return
+	}
+
+	// the lock isn't held while talking to iTerm2, so that readers aren't blocked
+	for _, name := range w.names {
+		initialValue, changes, err := monitorVariable(sessionCtx, w.conn, iterm2.VariableScope_SESSION,
+			state.SessionId, name)
+
+		if err != nil {
+			// usually the session is gone; forgetting it stops the monitors of its other variables
+			logrus.Debugf("watch variables: %s: %s", state.SessionId, err)
+			w.forget(state.SessionId)
+			return
+		}
+
+		w.update(VariableChange{Identifier: state.SessionId, Name: name, NewValue: initialValue})
+
+		w.wg.Add(1)
+		go func(changes <-chan VariableChange, first *VariableChange) {
+			defer w.wg.Done()
+
+			if first != nil {
+				w.changes <- *first
+			}
+
+			for change := range changes {
+				w.update(change)
+				w.changes <- change
+			}
+		}(changes, w.initialChange(announce, state.SessionId, name, initialValue))
+	}
+}
+
+// add starts tracking a session, returning a context that is cancelled when the session is forgotten, or false if the
+// session is already tracked.
+func (w *VariableWatcher) add(ctx context.Context, state *SessionState) (context.Context, bool) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if _, ok := w.sessions[state.SessionId]; ok {
+		return nil, false
+	}
+
+	sessionCtx, cancel := context.WithCancel(ctx)
+	state.lookup = nil
+	state.Variables = make(map[string]string)
+	w.sessions[state.SessionId] = &watchedSession{state: state, cancel: cancel}
+
+	return sessionCtx, true
+}
+
+func (w *VariableWatcher) initialChange(announce bool, sessionId, name, value string) *VariableChange {
+	if !announce {
+		return nil
+	}
+
+	return &VariableChange{
+		Scope:      iterm2.VariableScope_SESSION,
+		Identifier: sessionId,
+		Name:       name,
+		OldValue:   "null",
+		NewValue:   value,
+	}
+}
+
+func (w *VariableWatcher) update(change VariableChange) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if s, ok := w.sessions[change.Identifier]; ok {
+		s.state.Variables[change.Name], _ = variableString(change.NewValue)
+	}
+}
+
+func (w *VariableWatcher) forget(sessionId string) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	if s, ok := w.sessions[sessionId]; ok {
+		s.cancel()
+		delete(w.sessions, sessionId)
+	}
+}
+
+func (w *VariableWatcher) applyLayout(layout *iterm2.ListSessionsResponse) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+
+	for _, win := range layout.GetWindows() {
+		for _, tab := range win.GetTabs() {
+			for _, summary := range splitTreeSessions(tab.GetRoot()) {
+				if s, ok := w.sessions[summary.GetUniqueIdentifier()]; ok {
+					s.state.WindowId = win.GetWindowId()
+					s.state.TabId = tab.GetTabId()
+					s.state.Title = summary.GetTitle()
+					s.state.Buried = false
+				}
+			}
+		}
+	}
+
+	for _, summary := range layout.GetBuriedSessions() {
+		if s, ok := w.sessions[summary.GetUniqueIdentifier()]; ok {
+			s.state.WindowId = ""
+			s.state.TabId = ""
+			s.state.Buried = true
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package itermctl
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"mrz.io/itermctl/iterm2"
+	"testing"
+)
+
+func TestVariableWatcher_Bookkeeping(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := newVariableWatcher(nil)
+
+	sessionCtx, ok := w.add(ctx, &SessionState{SessionId: "s1"})
+	if !ok {
+		t.Fatal("expected s1 to be added")
+	}
+
+	if _, ok := w.add(ctx, &SessionState{SessionId: "s1"}); ok {
+		t.Fatal("expected s1 not to be added twice")
+	}
+
+	w.watch(ctx, &SessionState{SessionId: "s2"}, false)
+
+	w.update(VariableChange{Identifier: "s1", Name: "jobName", NewValue: `"vim"`})
+	w.update(VariableChange{Identifier: "unknown", Name: "jobName", NewValue: `"vim"`})
+
+	if value, ok := w.Value("s1", "jobName"); !ok || value != "vim" {
+		t.Fatalf("expected %q, got %q (%t)", "vim", value, ok)
+	}
+
+	if _, ok := w.Value("s2", "jobName"); ok {
+		t.Fatal("expected no value for s2")
+	}
+
+	if _, ok := w.Value("unknown", "jobName"); ok {
+		t.Fatal("expected unknown session to be ignored")
+	}
+
+	w.applyLayout(&iterm2.ListSessionsResponse{
+		Windows: []*iterm2.ListSessionsResponse_Window{{
+			WindowId: proto.String("w1"),
+			Tabs: []*iterm2.ListSessionsResponse_Tab{{
+				TabId: proto.String("t1"),
+				Root: &iterm2.SplitTreeNode{Links: []*iterm2.SplitTreeNode_SplitTreeLink{{
+					Child: &iterm2.SplitTreeNode_SplitTreeLink_Session{
+						Session: &iterm2.SessionSummary{UniqueIdentifier: proto.String("s1"), Title: proto.String("vim")},
+					},
+				}}},
+			}},
+		}},
+		BuriedSessions: []*iterm2.SessionSummary{{UniqueIdentifier: proto.String("s2")}},
+	})
+
+	states, err := w.SessionStates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(states) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(states))
+	}
+
+	if s := states[0]; s.SessionId != "s1" || s.WindowId != "w1" || s.TabId != "t1" || s.Title != "vim" || s.Buried {
+		t.Fatalf("unexpected state of s1: %+v", s)
+	}
+
+	if s := states[1]; s.SessionId != "s2" || !s.Buried {
+		t.Fatalf("unexpected state of s2: %+v", s)
+	}
+
+	states[0].Variables["jobName"] = "changed"
+	if value, _ := w.Value("s1", "jobName"); value != "vim" {
+		t.Fatal("expected SessionStates to return a copy")
+	}
+
+	w.forget("s1")
+	w.forget("unknown")
+
+	if sessionCtx.Err() == nil {
+		t.Fatal("expected the context of a forgotten session to be cancelled")
+	}
+
+	if _, ok := w.Value("s1", "jobName"); ok {
+		t.Fatal("expected s1 to be forgotten")
+	}
+
+	if _, ok := w.add(ctx, &SessionState{SessionId: "s1"}); !ok {
+		t.Fatal("expected a forgotten session to be added again")
+	}
+}
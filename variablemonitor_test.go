@@ -0,0 +1,36 @@
+package itermctl_test
+
+import (
+	"mrz.io/itermctl"
+	"testing"
+)
+
+func TestVariableChange_Strings(t *testing.T) {
+	change := itermctl.VariableChange{Name: "jobName", OldValue: "null", NewValue: `"vim"`}
+
+	oldValue, newValue, err := change.Strings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if oldValue != "" || newValue != "vim" {
+		t.Fatalf("expected %q -> %q, got %q -> %q", "", "vim", oldValue, newValue)
+	}
+}
+
+func TestVariableChange_Values(t *testing.T) {
+	change := itermctl.VariableChange{Name: "user.count", OldValue: "1", NewValue: "2"}
+
+	var oldValue, newValue int
+	if err := change.Values(&oldValue, &newValue); err != nil {
+		t.Fatal(err)
+	}
+
+	if oldValue != 1 || newValue != 2 {
+		t.Fatalf("expected 1 -> 2, got %d -> %d", oldValue, newValue)
+	}
+
+	if err := change.Values(nil, &newValue); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,225 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/internal/json"
+	"mrz.io/itermctl/iterm2"
+	"sort"
+)
+
+// Variable unmarshals the value of the named session variable, such as "path", "jobName" or "user.gitBranch", into
+// target. Unset variables unmarshal as JSON null.
+// See https://iterm2.com/documentation-variables.html.
+func (s *Session) Variable(name string, target interface{}) error {
+	return getVariable(s.conn, newVariableScope(iterm2.VariableScope_SESSION, s.id), name, target)
+}
+
+// Variables returns all the session's variables.
+func (s *Session) Variables() (map[string]interface{}, error) {
+	return getAllVariables(s.conn, newVariableScope(iterm2.VariableScope_SESSION, s.id))
+}
+
+// SetVariable sets a session's user variable; its name must begin with "user.".
+func (s *Session) SetVariable(name string, value interface{}) error {
+	return s.SetVariables(map[string]interface{}{name: value})
+}
+
+// SetVariables sets many of the session's user variables at once.
+func (s *Session) SetVariables(values map[string]interface{}) error {
+	return SetVariables(s.conn, iterm2.VariableScope_SESSION, s.id, values)
+}
+
+func (s *Session) getVariables(names ...string) ([]string, error) {
+	return getVariables(s.conn, newVariableScope(iterm2.VariableScope_SESSION, s.id), names...)
+}
+
+// Variable unmarshals the value of the named tab variable, such as "title" or "user.foo", into target.
+func (t *Tab) Variable(name string, target interface{}) error {
+	return getVariable(t.conn, newVariableScope(iterm2.VariableScope_TAB, t.id), name, target)
+}
+
+// Variables returns all the tab's variables.
+func (t *Tab) Variables() (map[string]interface{}, error) {
+	return getAllVariables(t.conn, newVariableScope(iterm2.VariableScope_TAB, t.id))
+}
+
+// SetVariable sets a tab's user variable; its name must begin with "user.".
+func (t *Tab) SetVariable(name string, value interface{}) error {
+	return t.SetVariables(map[string]interface{}{name: value})
+}
+
+// SetVariables sets many of the tab's user variables at once.
+func (t *Tab) SetVariables(values map[string]interface{}) error {
+	return SetVariables(t.conn, iterm2.VariableScope_TAB, t.id, values)
+}
+
+// Variable unmarshals the value of the named window variable, such as "frame" or "user.foo", into target.
+func (w *Window) Variable(name string, target interface{}) error {
+	return getVariable(w.conn, newVariableScope(iterm2.VariableScope_WINDOW, w.id), name, target)
+}
+
+// Variables returns all the window's variables.
+func (w *Window) Variables() (map[string]interface{}, error) {
+	return getAllVariables(w.conn, newVariableScope(iterm2.VariableScope_WINDOW, w.id))
+}
+
+// SetVariable sets a window's user variable; its name must begin with "user.".
+func (w *Window) SetVariable(name string, value interface{}) error {
+	return w.SetVariables(map[string]interface{}{name: value})
+}
+
+// SetVariables sets many of the window's user variables at once.
+func (w *Window) SetVariables(values map[string]interface{}) error {
+	return SetVariables(w.conn, iterm2.VariableScope_WINDOW, w.id, values)
+}
+
+// Variable unmarshals the value of the named app variable, such as "effectiveTheme" or "user.foo", into target.
+func (a *App) Variable(name string, target interface{}) error {
+	return getVariable(a.conn, newVariableScope(iterm2.VariableScope_APP, ""), name, target)
+}
+
+// Variables returns all the app's variables.
+func (a *App) Variables() (map[string]interface{}, error) {
+	return getAllVariables(a.conn, newVariableScope(iterm2.VariableScope_APP, ""))
+}
+
+// SetVariable sets an app's user variable; its name must begin with "user.".
+func (a *App) SetVariable(name string, value interface{}) error {
+	return a.SetVariables(map[string]interface{}{name: value})
+}
+
+// SetVariables sets many of the app's user variables at once.
+func (a *App) SetVariables(values map[string]interface{}) error {
+	return SetVariables(a.conn, iterm2.VariableScope_APP, "", values)
+}
+
+// SetVariables sets many user variables at once, in the session, tab or window with the given identifier. The
+// identifier can be "all" (see AllSessions) to set the variables in every session, tab or window with a single
+// request. The identifier is ignored for the APP scope.
+func SetVariables(conn *Connection, scope iterm2.VariableScope, identifier string, values map[string]interface{}) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	req := newVariableScope(scope, identifier)
+
+	for _, name := range names {
+		name := name
+		value, err := json.MarshalString(values[name])
+		if err != nil {
+			return fmt.Errorf("set variables: %q: %w", name, err)
+		}
+
+		req.Set = append(req.Set, &iterm2.VariableRequest_Set{Name: &name, Value: &value})
+	}
+
+	if _, err := sendVariableRequest(conn, req); err != nil {
+		return fmt.Errorf("set variables: %w", err)
+	}
+
+	return nil
+}
+
+// newVariableScope returns an empty VariableRequest addressing the given scope.
+func newVariableScope(scope iterm2.VariableScope, identifier string) *iterm2.VariableRequest {
+	req := &iterm2.VariableRequest{}
+
+	switch scope {
+	case iterm2.VariableScope_SESSION:
+		req.Scope = &iterm2.VariableRequest_SessionId{SessionId: identifier}
+	case iterm2.VariableScope_TAB:
+		req.Scope = &iterm2.VariableRequest_TabId{TabId: identifier}
+	case iterm2.VariableScope_WINDOW:
+		req.Scope = &iterm2.VariableRequest_WindowId{WindowId: identifier}
+	case iterm2.VariableScope_APP:
+		req.Scope = &iterm2.VariableRequest_App{App: true}
+	}
+
+	return req
+}
+
+func getVariable(conn *Connection, scope *iterm2.VariableRequest, name string, target interface{}) error {
+	values, err := getVariables(conn, scope, name)
+	if err != nil {
+		return err
+	}
+
+	if err := json.UnmarshalString(values[0], target); err != nil {
+		return fmt.Errorf("get variable %q: %w", name, err)
+	}
+
+	return nil
+}
+
+func getAllVariables(conn *Connection, scope *iterm2.VariableRequest) (map[string]interface{}, error) {
+	values, err := getVariables(conn, scope, "*")
+	if err != nil {
+		return nil, err
+	}
+
+	variables := make(map[string]interface{})
+	if err := json.UnmarshalString(values[0], &variables); err != nil {
+		return nil, fmt.Errorf("get variables: %w", err)
+	}
+
+	return variables, nil
+}
+
+func getVariables(conn *Connection, scope *iterm2.VariableRequest, names ...string) ([]string, error) {
+	scope.Get = names
+
+	resp, err := sendVariableRequest(conn, scope)
+	if err != nil {
+		return nil, fmt.Errorf("get variables: %w", err)
+	}
+
+	if len(resp.GetValues()) != len(names) {
+		return nil, fmt.Errorf("get variables: expected %d values, got %d", len(names), len(resp.GetValues()))
+	}
+
+	return resp.GetValues(), nil
+}
+
+func sendVariableRequest(conn *Connection, variableReq *iterm2.VariableRequest) (*iterm2.VariableResponse, error) {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_VariableRequest{
+			VariableRequest: variableReq,
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.GetVariableResponse().GetStatus() {
+	case iterm2.VariableResponse_OK:
+	case iterm2.VariableResponse_SESSION_NOT_FOUND:
+		return nil, ErrSessionNotFound
+	default:
+		return nil, fmt.Errorf("%s", resp.GetVariableResponse().GetStatus())
+	}
+
+	return resp.GetVariableResponse(), nil
+}
+
+// variableString turns a JSON-encoded variable value into the string used to compare it with other values: strings
+// are unquoted, null (unset variables) becomes the empty string, and anything else is left in its JSON form.
+func variableString(jsonValue string) (string, error) {
+	var value interface{}
+	if err := json.UnmarshalString(jsonValue, &value); err != nil {
+		return "", err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	default:
+		return jsonValue, nil
+	}
+}
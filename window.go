@@ -0,0 +1,291 @@
+package itermctl
+
+import (
+	"context"
+	"fmt"
+	"mrz.io/itermctl/iterm2"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTabAssignment = fmt.Errorf("ReorderTabsResponse_INVALID_ASSIGNMENT")
+	ErrInvalidWindowId      = fmt.Errorf("ReorderTabsResponse_INVALID_WINDOW_ID")
+	ErrInvalidTabId         = fmt.Errorf("ReorderTabsResponse_INVALID_TAB_ID")
+)
+
+// Window is a terminal window. Get one with App.Window.
+type Window struct {
+	id   string
+	app  *App
+	conn *Connection
+}
+
+// Id returns the window's ID.
+func (w *Window) Id() string {
+	return w.id
+}
+
+// TabKey is a key by which Window.SortTabs sorts tabs. Less is only called with keys returned by the same TabKeyFunc.
+type TabKey interface {
+	Less(other TabKey) bool
+}
+
+// StringKey is a TabKey ordering strings lexically.
+type StringKey string
+
+// Less implements TabKey.
+func (k StringKey) Less(other TabKey) bool {
+	return k < other.(StringKey)
+}
+
+// TimeKey is a TabKey ordering times chronologically.
+type TimeKey time.Time
+
+// Less implements TabKey.
+func (k TimeKey) Less(other TabKey) bool {
+	return time.Time(k).Before(time.Time(other.(TimeKey)))
+}
+
+// TabKeyFunc returns the key by which Window.SortTabs sorts a tab.
+type TabKeyFunc func(tab *Tab) (TabKey, error)
+
+// TabTitle is a TabKeyFunc sorting tabs by title, ignoring case.
+func TabTitle(tab *Tab) (TabKey, error) {
+	var title string
+	if err := tab.Variable("title", &title); err != nil {
+		return nil, err
+	}
+
+	return StringKey(strings.ToLower(title)), nil
+}
+
+// TabDirectory is a TabKeyFunc sorting tabs by the working directory of their current session. It requires shell
+// integration.
+func TabDirectory(tab *Tab) (TabKey, error) {
+	var path string
+	if err := tab.Variable("currentSession.path", &path); err != nil {
+		return nil, err
+	}
+
+	return StringKey(path), nil
+}
+
+// TabIds returns the IDs of the window's tabs, in order.
+func (w *Window) TabIds() ([]string, error) {
+	layout, err := w.app.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("tab ids: %w", err)
+	}
+
+	tabIds, ok := windowTabIds(layout, w.id)
+	if !ok {
+		return nil, fmt.Errorf("tab ids: %w: %s", ErrInvalidWindowId, w.id)
+	}
+
+	return tabIds, nil
+}
+
+// ReorderTabs moves the given tabs to the front of the window, in the given order; the other tabs follow in their
+// current order. Tabs from other windows are moved to this window, unless that would leave a window without tabs. Tabs
+// given more than once are rejected with ErrInvalidTabAssignment.
+func (w *Window) ReorderTabs(tabIds ...string) error {
+	layout, err := w.app.ListSessions()
+	if err != nil {
+		return fmt.Errorf("reorder tabs: %w", err)
+	}
+
+	current, ok := windowTabIds(layout, w.id)
+	if !ok {
+		return fmt.Errorf("reorder tabs: %w: %s", ErrInvalidWindowId, w.id)
+	}
+
+	order := append([]string{}, tabIds...)
+	listed := make(map[string]bool)
+	for _, id := range tabIds {
+		listed[id] = true
+	}
+
+	for _, id := range current {
+		if !listed[id] {
+			order = append(order, id)
+		}
+	}
+
+	assignments, err := reassignTabs(layout, w.id, order)
+	if err != nil {
+		return fmt.Errorf("reorder tabs: %w", err)
+	}
+
+	if err := sendReorderTabsRequest(w.conn, assignments); err != nil {
+		return fmt.Errorf("reorder tabs: %w", err)
+	}
+
+	return nil
+}
+
+// SortTabs orders the window's tabs by the keys returned by the given function, in ascending order or descending if
+// reverse is true. Tabs with equal keys keep their relative order.
+func (w *Window) SortTabs(key TabKeyFunc, reverse bool) error {
+	tabIds, err := w.TabIds()
+	if err != nil {
+		return fmt.Errorf("sort tabs: %w", err)
+	}
+
+	keys := make(map[string]TabKey)
+	for _, id := range tabIds {
+		k, err := key(w.app.Tab(id))
+		if err != nil {
+			return fmt.Errorf("sort tabs: tab %s: %w", id, err)
+		}
+		keys[id] = k
+	}
+
+	sortTabIds(tabIds, keys, reverse)
+
+	return w.ReorderTabs(tabIds...)
+}
+
+// sortTabIds sorts tab IDs by their keys, keeping the relative order of tabs with equal keys.
+func sortTabIds(tabIds []string, keys map[string]TabKey, reverse bool) {
+	sort.SliceStable(tabIds, func(i, j int) bool {
+		if reverse {
+			return keys[tabIds[j]].Less(keys[tabIds[i]])
+		}
+		return keys[tabIds[i]].Less(keys[tabIds[j]])
+	})
+}
+
+// MoveTab moves a tab to the given position of a window, which can be the window the tab is already in. Indexes past
+// the last tab put the tab at the end.
+func (a *App) MoveTab(tabId string, windowId string, index int) error {
+	layout, err := a.ListSessions()
+	if err != nil {
+		return fmt.Errorf("move tab: %w", err)
+	}
+
+	current, ok := windowTabIds(layout, windowId)
+	if !ok {
+		return fmt.Errorf("move tab: %w: %s", ErrInvalidWindowId, windowId)
+	}
+
+	var order []string
+	for _, id := range current {
+		if id != tabId {
+			order = append(order, id)
+		}
+	}
+
+	if index < 0 {
+		index = 0
+	}
+
+	if index > len(order) {
+		index = len(order)
+	}
+
+	order = append(order[:index], append([]string{tabId}, order[index:]...)...)
+
+	assignments, err := reassignTabs(layout, windowId, order)
+	if err != nil {
+		return fmt.Errorf("move tab: %w", err)
+	}
+
+	if err := sendReorderTabsRequest(a.conn, assignments); err != nil {
+		return fmt.Errorf("move tab: %w", err)
+	}
+
+	return nil
+}
+
+func windowTabIds(layout *iterm2.ListSessionsResponse, windowId string) ([]string, bool) {
+	for _, win := range layout.GetWindows() {
+		if win.GetWindowId() != windowId {
+			continue
+		}
+
+		tabIds := []string{}
+		for _, tab := range win.GetTabs() {
+			tabIds = append(tabIds, tab.GetTabId())
+		}
+
+		return tabIds, true
+	}
+
+	return nil, false
+}
+
+// reassignTabs returns the assignments giving a window the given tabs, in order, and removing them from the other
+// windows they're in. It fails if a tab is given twice, or if a window would be left without tabs.
+func reassignTabs(layout *iterm2.ListSessionsResponse, windowId string,
+	tabIds []string) ([]*iterm2.ReorderTabsRequest_Assignment, error) {
+
+	moving := make(map[string]bool)
+	for _, id := range tabIds {
+		if moving[id] {
+			return nil, fmt.Errorf("%w: duplicate tab %s", ErrInvalidTabAssignment, id)
+		}
+		moving[id] = true
+	}
+
+	if len(tabIds) == 0 {
+		return nil, fmt.Errorf("%w: window %s would have no tabs", ErrInvalidTabAssignment, windowId)
+	}
+
+	assignments := []*iterm2.ReorderTabsRequest_Assignment{{WindowId: &windowId, TabIds: tabIds}}
+
+	for _, win := range layout.GetWindows() {
+		if win.GetWindowId() == windowId {
+			continue
+		}
+
+		var remaining []string
+		changed := false
+
+		for _, tab := range win.GetTabs() {
+			if moving[tab.GetTabId()] {
+				changed = true
+			} else {
+				remaining = append(remaining, tab.GetTabId())
+			}
+		}
+
+		if changed && len(remaining) == 0 {
+			return nil, fmt.Errorf("%w: window %s would have no tabs", ErrInvalidTabAssignment, win.GetWindowId())
+		}
+
+		if changed {
+			id := win.GetWindowId()
+			assignments = append(assignments, &iterm2.ReorderTabsRequest_Assignment{WindowId: &id, TabIds: remaining})
+		}
+	}
+
+	return assignments, nil
+}
+
+func sendReorderTabsRequest(conn *Connection, assignments []*iterm2.ReorderTabsRequest_Assignment) error {
+	req := &iterm2.ClientOriginatedMessage{
+		Submessage: &iterm2.ClientOriginatedMessage_ReorderTabsRequest{
+			ReorderTabsRequest: &iterm2.ReorderTabsRequest{Assignments: assignments},
+		},
+	}
+
+	resp, err := conn.GetResponse(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	switch resp.GetReorderTabsResponse().GetStatus() {
+	case iterm2.ReorderTabsResponse_OK:
+		return nil
+	case iterm2.ReorderTabsResponse_INVALID_ASSIGNMENT:
+		return ErrInvalidTabAssignment
+	case iterm2.ReorderTabsResponse_INVALID_WINDOW_ID:
+		return ErrInvalidWindowId
+	case iterm2.ReorderTabsResponse_INVALID_TAB_ID:
+		return ErrInvalidTabId
+	default:
+		return fmt.Errorf("%s", resp.GetReorderTabsResponse().GetStatus())
+	}
+}
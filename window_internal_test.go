@@ -0,0 +1,85 @@
+package itermctl
+
+import (
+	"errors"
+	"github.com/golang/protobuf/proto"
+	"mrz.io/itermctl/iterm2"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testWindow(id string, tabIds ...string) *iterm2.ListSessionsResponse_Window {
+	win := &iterm2.ListSessionsResponse_Window{WindowId: proto.String(id)}
+	for _, tabId := range tabIds {
+		win.Tabs = append(win.Tabs, &iterm2.ListSessionsResponse_Tab{TabId: proto.String(tabId)})
+	}
+	return win
+}
+
+func TestReassignTabs(t *testing.T) {
+	layout := &iterm2.ListSessionsResponse{
+		Windows: []*iterm2.ListSessionsResponse_Window{
+			testWindow("w1", "1", "2"),
+			testWindow("w2", "3", "4"),
+			testWindow("w3", "5"),
+		},
+	}
+
+	assignments, err := reassignTabs(layout, "w1", []string{"3", "1", "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	for _, a := range assignments {
+		got[a.GetWindowId()] = a.GetTabIds()
+	}
+
+	expected := map[string][]string{"w1": {"3", "1", "2"}, "w2": {"4"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	invalid := [][]string{
+		{"1", "2", "1"},
+		{"5", "1", "2"},
+		{},
+	}
+
+	for _, tabIds := range invalid {
+		if _, err := reassignTabs(layout, "w1", tabIds); !errors.Is(err, ErrInvalidTabAssignment) {
+			t.Errorf("%v: expected %v, got %v", tabIds, ErrInvalidTabAssignment, err)
+		}
+	}
+}
+
+func TestSortTabIds(t *testing.T) {
+	epoch := time.Unix(0, 0)
+
+	keys := map[string]TabKey{
+		"never":  TimeKey(time.Time{}),
+		"1969":   TimeKey(epoch.Add(-time.Hour)),
+		"1970":   TimeKey(epoch),
+		"recent": TimeKey(epoch.Add(50 * 365 * 24 * time.Hour)),
+		"same":   TimeKey(epoch),
+	}
+
+	tabIds := []string{"recent", "1970", "never", "same", "1969"}
+
+	sortTabIds(tabIds, keys, false)
+	if expected := []string{"never", "1969", "1970", "same", "recent"}; !reflect.DeepEqual(tabIds, expected) {
+		t.Fatalf("expected %v, got %v", expected, tabIds)
+	}
+
+	sortTabIds(tabIds, keys, true)
+	if expected := []string{"recent", "1970", "same", "1969", "never"}; !reflect.DeepEqual(tabIds, expected) {
+		t.Fatalf("expected %v, got %v", expected, tabIds)
+	}
+
+	tabIds = []string{"b", "A", "a"}
+	sortTabIds(tabIds, map[string]TabKey{"b": StringKey("b"), "A": StringKey("a"), "a": StringKey("a")}, false)
+	if expected := []string{"A", "a", "b"}; !reflect.DeepEqual(tabIds, expected) {
+		t.Fatalf("expected %v, got %v", expected, tabIds)
+	}
+}